@@ -1,35 +1,151 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+	_ "time/tzdata" // digest schedules may name any IANA timezone
 
+	"tldr-telegram-bot/internal/app"
 	"tldr-telegram-bot/internal/config"
 	"tldr-telegram-bot/internal/db"
+	"tldr-telegram-bot/internal/janitor"
+	"tldr-telegram-bot/internal/scheduler"
 	"tldr-telegram-bot/internal/telegram"
+	"tldr-telegram-bot/internal/transcribe"
 
 	"github.com/joho/godotenv"
 )
 
+// cancelGracePeriod is how long cancelled work has to return once the shutdown
+// timeout expired.
+const cancelGracePeriod = 5 * time.Second
+
 func main() {
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
 		log.Fatalf("Error loading .env file: %v", err)
 	}
 
+	// SIGINT and SIGTERM (docker stop) start a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Subcommands only need the database
+	if len(os.Args) > 1 {
+		var err error
+		switch os.Args[1] {
+		case "migrate":
+			err = runMigrate(ctx, os.Args[2:])
+		case "rotate-keys":
+			err = runRotateKeys(ctx, os.Args[2:])
+		case "decrypt":
+			err = runDecrypt(ctx, os.Args[2:])
+		default:
+			err = fmt.Errorf("unknown command %q\n%s\n%s\n%s", os.Args[1], migrateUsage, rotateKeysUsage, decryptUsage)
+		}
+		if err != nil {
+			// Reached once the subcommand has closed the database.
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Validate configuration
 	if err := config.Validate(); err != nil {
 		log.Fatalf("Configuration validation error: %v", err)
 	}
 
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+	if cfg.Transcriber != "" {
+		if _, err := transcribe.New(cfg.Transcriber); err != nil {
+			log.Fatalf("Error initializing transcription backend: %v", err)
+		}
+	}
+
 	// Initialize database
 	db.InitDB()
 
-	// Start the Telegram bot
-	bot, err := telegram.NewBot()
+	// Create the dependencies shared by every handler, once
+	application, err := app.New(cfg, db.GetStore())
 	if err != nil {
-		log.Fatalf("Error initializing Telegram bot: %v", err)
+		log.Fatalf("Error initializing application: %v", err)
+	}
+	bot := telegram.NewBot(application)
+
+	// Work in progress when a signal arrives keeps running on work, which is only
+	// cancelled if it is still running once the shutdown timeout expires.
+	work, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWork()
+
+	var background sync.WaitGroup
+
+	// Start the digest scheduler
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		digest := func(groupID int64, since, until time.Time) error {
+			return bot.SendDigest(work, groupID, since, until)
+		}
+		scheduler.New(application.Store, digest).Run(ctx)
+	}()
+
+	// Start the retention janitor
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		janitor.New(application.Store, cfg.RetentionDays).Run(ctx)
+	}()
+
+	// Serve metrics, when enabled
+	if cfg.MetricsAddr != "" {
+		go serveMetrics(ctx, cfg.MetricsAddr)
 	}
 
 	log.Println("Bot started and listening for messages...")
-	bot.Start()
+	// A failure to receive updates still shuts down the normal way, draining the
+	// work in progress, before the process exits with an error.
+	startErr := bot.Start(ctx, work)
+	if startErr != nil {
+		log.Printf("Error receiving updates: %v", startErr)
+	}
+
+	// From here on, a second signal terminates the process right away.
+	stop()
+
+	log.Printf("Shutting down, waiting up to %s for work in progress...", cfg.ShutdownTimeout)
+	drained := make(chan struct{})
+	go func() {
+		bot.Wait()
+		background.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		log.Println("Work in progress finished.")
+	case <-time.After(cfg.ShutdownTimeout):
+		log.Println("Shutdown timeout expired, cancelling work in progress.")
+		cancelWork()
+		// Cancelled work may still be writing its results; give it a moment to
+		// return before the database goes away under it.
+		select {
+		case <-drained:
+			log.Println("Work in progress cancelled.")
+		case <-time.After(cancelGracePeriod):
+			log.Println("Work in progress did not stop after cancellation, closing anyway.")
+		}
+	}
+
+	db.CloseDB()
+	if startErr != nil {
+		os.Exit(1)
+	}
 }
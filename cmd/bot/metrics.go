@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"log"
+	"net/http"
+)
+
+// serveMetrics serves the expvar variables, including the dispatcher's queue
+// metrics, on addr under /debug/vars until ctx is cancelled.
+func serveMetrics(ctx context.Context, addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	log.Printf("Serving metrics on %s/debug/vars", addr)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("Metrics server stopped: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"tldr-telegram-bot/internal/db"
+)
+
+const migrateUsage = "usage: tldr-telegram-bot migrate [up | down [steps] | status]"
+
+// runMigrate implements the "migrate" subcommand.
+func runMigrate(ctx context.Context, args []string) error {
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+	steps := 1
+	switch action {
+	case "up", "status":
+	case "down":
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				return errors.New(migrateUsage)
+			}
+			steps = n
+		}
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	db.OpenDB()
+	defer db.CloseDB()
+
+	switch action {
+	case "up":
+		applied, err := db.GetStore().MigrateUp(ctx)
+		if err != nil {
+			return fmt.Errorf("migrating database: %w", err)
+		}
+		log.Printf("Applied %d migration(s)", applied)
+
+	case "down":
+		reverted, err := db.GetStore().MigrateDown(ctx, steps)
+		if err != nil {
+			return fmt.Errorf("reverting migrations: %w", err)
+		}
+		log.Printf("Reverted %d migration(s)", reverted)
+
+	case "status":
+		states, err := db.GetStore().MigrationStatus(ctx)
+		if err != nil {
+			return fmt.Errorf("reading migration status: %w", err)
+		}
+		for _, state := range states {
+			applied := "pending"
+			if !state.AppliedAt.IsZero() {
+				applied = state.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-40s %s\n", state.Version, state.Name, applied)
+		}
+	}
+	return nil
+}
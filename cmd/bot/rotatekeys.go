@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"tldr-telegram-bot/internal/db"
+)
+
+const rotateKeysUsage = "usage: tldr-telegram-bot rotate-keys [batch size]"
+
+const decryptUsage = "usage: tldr-telegram-bot decrypt [batch size]"
+
+// rotateKeysBatchSize is how many rows are read at a time by default.
+const rotateKeysBatchSize = 500
+
+// runRotateKeys implements the "rotate-keys" subcommand: every stored message is moved
+// to the first key of ENCRYPTION_KEYS, encrypting the ones still in plaintext.
+func runRotateKeys(ctx context.Context, args []string) error {
+	batchSize, err := parseBatchSize(args, rotateKeysUsage)
+	if err != nil {
+		return err
+	}
+
+	db.OpenDB()
+	defer db.CloseDB()
+
+	rotation, err := db.GetStore().RotateKeys(ctx, batchSize)
+	if err != nil {
+		return fmt.Errorf("rotating keys after %d row(s): %w", rotation.Updated, err)
+	}
+	log.Printf("Re-encrypted %d row(s), retried %d edited while being rewritten", rotation.Updated, rotation.Skipped)
+	return nil
+}
+
+// runDecrypt implements the "decrypt" subcommand: every encrypted message is stored
+// back in plaintext, as required before disabling encryption or reverting its migration.
+func runDecrypt(ctx context.Context, args []string) error {
+	batchSize, err := parseBatchSize(args, decryptUsage)
+	if err != nil {
+		return err
+	}
+
+	db.OpenDB()
+	defer db.CloseDB()
+
+	rotation, err := db.GetStore().DecryptAll(ctx, batchSize)
+	if err != nil {
+		return fmt.Errorf("decrypting after %d row(s): %w", rotation.Updated, err)
+	}
+	log.Printf("Decrypted %d row(s), retried %d edited while being rewritten", rotation.Updated, rotation.Skipped)
+	return nil
+}
+
+// parseBatchSize reads the optional batch size argument of a subcommand.
+func parseBatchSize(args []string, usage string) (int, error) {
+	if len(args) == 0 {
+		return rotateKeysBatchSize, nil
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n <= 0 {
+		return 0, errors.New(usage)
+	}
+	return n, nil
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"slices"
+
+	"tldr-telegram-bot/internal/config"
+	"tldr-telegram-bot/internal/db"
+	"tldr-telegram-bot/internal/llm"
+	"tldr-telegram-bot/internal/sender"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// App holds the long-lived dependencies shared by every handler. It is created once
+// at startup, so handling an update costs no extra Telegram or database round trip.
+type App struct {
+	// API is the authorized Telegram client.
+	API *tgbotapi.BotAPI
+	// Store is the open, migrated message store.
+	Store db.MessageStore
+	// Config holds the environment defaults; group settings override them per group.
+	Config *config.Config
+	// Summarizer is the provider chain configured by LLM_PROVIDER and LLM_FALLBACKS.
+	Summarizer *llm.Chain
+	// Sender posts to chats within Telegram's rate limits; every post goes through it.
+	Sender *sender.Sender
+}
+
+// New authorizes the bot with Telegram and builds the default summarizer chain.
+func New(cfg *config.Config, store db.MessageStore) (*App, error) {
+	if cfg.TelegramBotToken == "" {
+		return nil, fmt.Errorf("TELEGRAM_BOT_TOKEN is required")
+	}
+
+	api, err := tgbotapi.NewBotAPI(cfg.TelegramBotToken)
+	if err != nil {
+		return nil, fmt.Errorf("creating Telegram bot: %w", err)
+	}
+	api.Debug = false
+	log.Printf("Authorized on account %s", api.Self.UserName)
+
+	summarizer, err := llm.NewChain(cfg.LLMChain(), cfg.LLMTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("initializing LLM provider: %w", err)
+	}
+
+	return &App{API: api, Store: store, Config: cfg, Summarizer: summarizer, Sender: sender.New(api)}, nil
+}
+
+// SummarizerFor returns the chain serving a group's configuration: the shared one,
+// unless the group picked another provider.
+func (a *App) SummarizerFor(groupConfig *config.Config) (*llm.Chain, error) {
+	if slices.Equal(groupConfig.LLMChain(), a.Config.LLMChain()) {
+		return a.Summarizer, nil
+	}
+	return llm.NewChain(groupConfig.LLMChain(), groupConfig.LLMTimeout)
+}
@@ -5,17 +5,116 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
 	TelegramBotToken string
+	// Mode selects how updates are received: ModePolling or ModeWebhook.
+	Mode string
+	// WebhookListen is the address the webhook server listens on.
+	WebhookListen string
+	// WebhookURL is the public HTTPS URL Telegram posts updates to.
+	WebhookURL string
+	// WebhookSecret is the secret token Telegram sends with every webhook request.
+	WebhookSecret string
+	// WebhookTLSCert and WebhookTLSKey make the webhook server terminate TLS itself.
+	WebhookTLSCert   string
+	WebhookTLSKey    string
 	Lang             string
 	OllamaModel      string
 	AuthorizedGroups []int64
+	LLMProvider      string
+	LLMFallbacks     []string
+	LLMTimeout       time.Duration
+	// LLMContextTokens is the context window of the smallest model in the chain.
+	LLMContextTokens int
+	// Timezone is the IANA name of the timezone digests are scheduled in by default.
+	Timezone string
+	// Window is how far a summary reaches when no explicit range is given.
+	Window time.Duration
+	// Style selects the summary style (see llm.Styles); empty uses the plain prompt.
+	Style string
+	// RetentionDays is how long logged messages are kept; zero keeps them forever.
+	RetentionDays int
+	// Redact selects what personal data is removed before prompting (see redact.Modes).
+	Redact string
+	// Transcriber is the speech-to-text backend for voice messages; empty disables transcription.
+	Transcriber string
+	// DescribeImages adds descriptions of the photos in a range to the transcript.
+	DescribeImages bool
+	// MaxImages caps how many photos are described per summary.
+	MaxImages int
+	// LinkPreviews adds the title and description of shared pages to the transcript.
+	LinkPreviews bool
+	// MaxLinks caps how many pages are fetched per summary.
+	MaxLinks int
+	// LinkPreviewTimeout bounds the download of the linked pages of one summary.
+	LinkPreviewTimeout time.Duration
+	// LinkPreviewMaxBytes is how much of a page is read.
+	LinkPreviewMaxBytes int
+	// LinkPreviewAllowPrivate lets previews reach loopback and private addresses.
+	LinkPreviewAllowPrivate bool
+	// TranscribeTimeout bounds the download and transcription of one voice message.
+	TranscribeTimeout time.Duration
+	// ShutdownTimeout bounds how long in-flight work may run after a shutdown signal.
+	ShutdownTimeout time.Duration
+	// Workers is how many updates are processed at the same time.
+	Workers int
+	// QueueSize is how many updates may be queued or processed before intake blocks.
+	QueueSize int
+	// TaskWorkers is how many summaries and transcriptions run at the same time.
+	TaskWorkers int
+	// MetricsAddr is the address serving /debug/vars; empty disables it.
+	MetricsAddr string
 }
 
+// Update modes.
+const (
+	ModePolling = "polling"
+	ModeWebhook = "webhook"
+)
+
+// defaultWebhookListen is the webhook server address when WEBHOOK_LISTEN is not set.
+const defaultWebhookListen = ":8443"
+
+// defaultLLMTimeout bounds a single provider call when LLM_TIMEOUT is not set.
+const defaultLLMTimeout = 60 * time.Second
+
+// defaultTimezone is the timezone used when TIMEZONE is not set.
+const defaultTimezone = "UTC"
+
+// defaultWindow is the summary window used when SUMMARY_WINDOW is not set.
+const defaultWindow = 30 * time.Minute
+
+// defaultTranscribeTimeout bounds a transcription when TRANSCRIBE_TIMEOUT is not set.
+const defaultTranscribeTimeout = 2 * time.Minute
+
+// defaultShutdownTimeout bounds the shutdown drain when SHUTDOWN_TIMEOUT is not set.
+const defaultShutdownTimeout = 30 * time.Second
+
+// Dispatcher limits used when WORKERS, QUEUE_SIZE or TASK_WORKERS are not set.
+const (
+	defaultWorkers     = 8
+	defaultQueueSize   = 1000
+	defaultTaskWorkers = 4
+)
+
+// defaultMaxImages is the number of photos described per summary when MAX_IMAGES is not set.
+const defaultMaxImages = 5
+
+// Link preview limits used when the corresponding variables are not set.
+const (
+	defaultMaxLinks            = 5
+	defaultLinkPreviewTimeout  = 5 * time.Second
+	defaultLinkPreviewMaxBytes = 512 << 10
+)
+
+// defaultLLMContextTokens matches the default context window of Ollama models.
+const defaultLLMContextTokens = 4096
+
 func LoadConfig() (*Config, error) {
 	err := godotenv.Load()
 	if err != nil {
@@ -26,13 +125,92 @@ func LoadConfig() (*Config, error) {
 	groupIDs := parseAuthorizedGroups(authorizedGroups)
 
 	return &Config{
-		TelegramBotToken: os.Getenv("TELEGRAM_BOT_TOKEN"),
-		Lang:             os.Getenv("DEFAULT_LANG"),
-		OllamaModel:      os.Getenv("OLLAMA_MODEL"),
-		AuthorizedGroups: groupIDs,
+		TelegramBotToken:        os.Getenv("TELEGRAM_BOT_TOKEN"),
+		Mode:                    telegramMode(),
+		WebhookListen:           envOr("WEBHOOK_LISTEN", defaultWebhookListen),
+		WebhookURL:              os.Getenv("WEBHOOK_URL"),
+		WebhookSecret:           os.Getenv("WEBHOOK_SECRET"),
+		WebhookTLSCert:          os.Getenv("WEBHOOK_TLS_CERT"),
+		WebhookTLSKey:           os.Getenv("WEBHOOK_TLS_KEY"),
+		Lang:                    os.Getenv("DEFAULT_LANG"),
+		OllamaModel:             os.Getenv("OLLAMA_MODEL"),
+		AuthorizedGroups:        groupIDs,
+		LLMProvider:             llmProvider(),
+		LLMFallbacks:            splitList(os.Getenv("LLM_FALLBACKS")),
+		LLMTimeout:              parseDuration(os.Getenv("LLM_TIMEOUT"), defaultLLMTimeout),
+		LLMContextTokens:        parseInt(os.Getenv("LLM_CONTEXT_TOKENS"), defaultLLMContextTokens),
+		Timezone:                envOr("TIMEZONE", defaultTimezone),
+		Window:                  parseDuration(os.Getenv("SUMMARY_WINDOW"), defaultWindow),
+		Style:                   strings.ToLower(strings.TrimSpace(os.Getenv("SUMMARY_STYLE"))),
+		RetentionDays:           parseIntAtLeast(os.Getenv("RETENTION_DAYS"), 0, 0), // 0 keeps messages forever
+		Redact:                  redactMode(),
+		Transcriber:             strings.ToLower(strings.TrimSpace(os.Getenv("TRANSCRIBE_PROVIDER"))),
+		TranscribeTimeout:       parseDuration(os.Getenv("TRANSCRIBE_TIMEOUT"), defaultTranscribeTimeout),
+		DescribeImages:          os.Getenv("DESCRIBE_IMAGES") == "true",
+		MaxImages:               parseInt(os.Getenv("MAX_IMAGES"), defaultMaxImages),
+		LinkPreviews:            os.Getenv("LINK_PREVIEWS") == "true",
+		MaxLinks:                parseInt(os.Getenv("MAX_LINKS"), defaultMaxLinks),
+		LinkPreviewTimeout:      parseDuration(os.Getenv("LINK_PREVIEW_TIMEOUT"), defaultLinkPreviewTimeout),
+		LinkPreviewMaxBytes:     parseInt(os.Getenv("LINK_PREVIEW_MAX_BYTES"), defaultLinkPreviewMaxBytes),
+		LinkPreviewAllowPrivate: os.Getenv("LINK_PREVIEW_ALLOW_PRIVATE") == "true",
+		ShutdownTimeout:         parseDuration(os.Getenv("SHUTDOWN_TIMEOUT"), defaultShutdownTimeout),
+		Workers:                 parseInt(os.Getenv("WORKERS"), defaultWorkers),
+		QueueSize:               parseInt(os.Getenv("QUEUE_SIZE"), defaultQueueSize),
+		TaskWorkers:             parseInt(os.Getenv("TASK_WORKERS"), defaultTaskWorkers),
+		MetricsAddr:             os.Getenv("METRICS_ADDR"),
 	}, nil
 }
 
+// LLMChain returns the primary provider followed by its fallbacks, in order.
+func (c *Config) LLMChain() []string {
+	return append([]string{c.LLMProvider}, c.LLMFallbacks...)
+}
+
+// Location returns the timezone named by Timezone, or UTC when it cannot be loaded.
+func (c *Config) Location() *time.Location {
+	if !IsValidTimezone(c.Timezone) {
+		return time.UTC
+	}
+	loc, _ := time.LoadLocation(c.Timezone)
+	return loc
+}
+
+// llmProvider returns the configured summarization backend. LLM_PROVIDER takes
+// precedence; otherwise the legacy LOCAL_MODEL flag picks between Ollama and Gemini.
+func llmProvider() string {
+	if provider := strings.TrimSpace(os.Getenv("LLM_PROVIDER")); provider != "" {
+		return strings.ToLower(provider)
+	}
+	if os.Getenv("LOCAL_MODEL") == "true" {
+		return "ollama"
+	}
+	return "gemini"
+}
+
+// telegramMode returns TELEGRAM_MODE, defaulting to long polling.
+func telegramMode() string {
+	if mode := strings.ToLower(strings.TrimSpace(os.Getenv("TELEGRAM_MODE"))); mode != "" {
+		return mode
+	}
+	return ModePolling
+}
+
+// envOr returns the value of the environment variable key, or fallback when it is empty.
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// redactMode returns REDACT_MODE, defaulting to "off".
+func redactMode() string {
+	if mode := strings.ToLower(strings.TrimSpace(os.Getenv("REDACT_MODE"))); mode != "" {
+		return mode
+	}
+	return "off"
+}
+
 func parseAuthorizedGroups(groups string) []int64 {
 	var groupIDs []int64
 	for _, group := range strings.Split(groups, ",") {
@@ -48,3 +226,46 @@ func parseAuthorizedGroups(groups string) []int64 {
 	}
 	return groupIDs
 }
+
+// splitList splits a comma-separated value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseDuration parses value as a time.Duration, returning fallback when it is empty or invalid.
+func parseDuration(value string, fallback time.Duration) time.Duration {
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Invalid duration %q, using %s", value, fallback)
+		return fallback
+	}
+	return d
+}
+
+// parseInt parses value as a positive integer, returning fallback when it is empty or invalid.
+func parseInt(value string, fallback int) int {
+	return parseIntAtLeast(value, fallback, 1)
+}
+
+// parseIntAtLeast parses value as an integer no smaller than min, returning fallback
+// when it is empty or invalid.
+func parseIntAtLeast(value string, fallback, min int) int {
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min {
+		log.Printf("Invalid number %q, using %d", value, fallback)
+		return fallback
+	}
+	return n
+}
@@ -2,9 +2,15 @@ package config
 
 import (
 	"errors"
+	"net/url"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
+
+	"tldr-telegram-bot/internal/llm"
+	"tldr-telegram-bot/internal/redact"
 )
 
 // Validate checks the required environment variables and their values.
@@ -12,7 +18,6 @@ func Validate() error {
 	requiredVars := []string{
 		"TELEGRAM_BOT_TOKEN",
 		"DEFAULT_LANG",
-		"OLLAMA_MODEL",
 		"AUTHORIZED_GROUPS",
 	}
 
@@ -24,7 +29,7 @@ func Validate() error {
 
 	// Validate DEFAULT_LANG
 	Lang := os.Getenv("DEFAULT_LANG")
-	if !isValidLanguage(Lang) {
+	if !IsValidLanguage(Lang) {
 		return errors.New("invalid DEFAULT_LANG value: " + Lang)
 	}
 
@@ -33,11 +38,105 @@ func Validate() error {
 		return err
 	}
 
+	// Validate TELEGRAM_MODE and the webhook settings
+	if err := validateWebhook(); err != nil {
+		return err
+	}
+
+	// Validate LLM_TIMEOUT
+	if timeout := os.Getenv("LLM_TIMEOUT"); timeout != "" {
+		if d, err := time.ParseDuration(timeout); err != nil || d <= 0 {
+			return errors.New("invalid LLM_TIMEOUT value: " + timeout)
+		}
+	}
+
+	// Validate TIMEZONE
+	if timezone := os.Getenv("TIMEZONE"); timezone != "" && !IsValidTimezone(timezone) {
+		return errors.New("invalid TIMEZONE value: " + timezone)
+	}
+
+	// Validate SUMMARY_WINDOW
+	if window := os.Getenv("SUMMARY_WINDOW"); window != "" {
+		if d, err := time.ParseDuration(window); err != nil || d <= 0 {
+			return errors.New("invalid SUMMARY_WINDOW value: " + window)
+		}
+	}
+
+	// Validate RETENTION_DAYS
+	if days := os.Getenv("RETENTION_DAYS"); days != "" {
+		if n, err := strconv.Atoi(days); err != nil || n < 0 {
+			return errors.New("invalid RETENTION_DAYS value: " + days)
+		}
+	}
+
+	// Validate LLM_CONTEXT_TOKENS
+	if tokens := os.Getenv("LLM_CONTEXT_TOKENS"); tokens != "" {
+		if n, err := strconv.Atoi(tokens); err != nil || n <= 0 {
+			return errors.New("invalid LLM_CONTEXT_TOKENS value: " + tokens)
+		}
+	}
+
+	// Validate TRANSCRIBE_TIMEOUT
+	if timeout := os.Getenv("TRANSCRIBE_TIMEOUT"); timeout != "" {
+		if d, err := time.ParseDuration(timeout); err != nil || d <= 0 {
+			return errors.New("invalid TRANSCRIBE_TIMEOUT value: " + timeout)
+		}
+	}
+
+	// Validate SHUTDOWN_TIMEOUT
+	if timeout := os.Getenv("SHUTDOWN_TIMEOUT"); timeout != "" {
+		if d, err := time.ParseDuration(timeout); err != nil || d <= 0 {
+			return errors.New("invalid SHUTDOWN_TIMEOUT value: " + timeout)
+		}
+	}
+
+	// Validate WORKERS, QUEUE_SIZE and TASK_WORKERS
+	for _, name := range []string{"WORKERS", "QUEUE_SIZE", "TASK_WORKERS"} {
+		if value := os.Getenv(name); value != "" {
+			if n, err := strconv.Atoi(value); err != nil || n <= 0 {
+				return errors.New("invalid " + name + " value: " + value)
+			}
+		}
+	}
+
+	// Validate MAX_IMAGES
+	if images := os.Getenv("MAX_IMAGES"); images != "" {
+		if n, err := strconv.Atoi(images); err != nil || n <= 0 {
+			return errors.New("invalid MAX_IMAGES value: " + images)
+		}
+	}
+
+	// Validate LINK_PREVIEW_TIMEOUT
+	if timeout := os.Getenv("LINK_PREVIEW_TIMEOUT"); timeout != "" {
+		if d, err := time.ParseDuration(timeout); err != nil || d <= 0 {
+			return errors.New("invalid LINK_PREVIEW_TIMEOUT value: " + timeout)
+		}
+	}
+
+	// Validate MAX_LINKS and LINK_PREVIEW_MAX_BYTES
+	for _, name := range []string{"MAX_LINKS", "LINK_PREVIEW_MAX_BYTES"} {
+		if value := os.Getenv(name); value != "" {
+			if n, err := strconv.Atoi(value); err != nil || n <= 0 {
+				return errors.New("invalid " + name + " value: " + value)
+			}
+		}
+	}
+
+	// Validate SUMMARY_STYLE
+	if style := os.Getenv("SUMMARY_STYLE"); style != "" && !llm.IsValidStyle(strings.ToLower(strings.TrimSpace(style))) {
+		return errors.New("invalid SUMMARY_STYLE value: " + style)
+	}
+
+	// Validate REDACT_MODE
+	if mode := os.Getenv("REDACT_MODE"); mode != "" && !redact.IsValidMode(strings.ToLower(strings.TrimSpace(mode))) {
+		return errors.New("invalid REDACT_MODE value: " + mode)
+	}
+
 	return nil
 }
 
-// isValidLanguage checks if the provided language is one of the allowed values.
-func isValidLanguage(lang string) bool {
+// IsValidLanguage checks if the provided language is one of the allowed values.
+func IsValidLanguage(lang string) bool {
 	allowedLanguages := []string{"pt", "en", "es"}
 	for _, l := range allowedLanguages {
 		if lang == l {
@@ -47,6 +146,42 @@ func isValidLanguage(lang string) bool {
 	return false
 }
 
+// IsValidTimezone checks that name is an IANA timezone. "Local" is refused: it depends
+// on the host the bot happens to run on.
+func IsValidTimezone(name string) bool {
+	if name == "" || strings.EqualFold(name, "local") {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+var webhookSecretPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// validateWebhook checks TELEGRAM_MODE and, in webhook mode, the settings it needs.
+func validateWebhook() error {
+	switch mode := strings.ToLower(strings.TrimSpace(os.Getenv("TELEGRAM_MODE"))); mode {
+	case "", ModePolling:
+		return nil
+	case ModeWebhook:
+	default:
+		return errors.New("invalid TELEGRAM_MODE value: " + mode)
+	}
+
+	webhookURL, err := url.Parse(os.Getenv("WEBHOOK_URL"))
+	if err != nil || webhookURL.Scheme != "https" || webhookURL.Host == "" {
+		return errors.New("WEBHOOK_URL must be an https:// URL in webhook mode")
+	}
+	// Telegram only accepts these characters in secret tokens.
+	if secret := os.Getenv("WEBHOOK_SECRET"); !webhookSecretPattern.MatchString(secret) {
+		return errors.New("WEBHOOK_SECRET must be 1-256 characters among A-Z, a-z, 0-9, _ and - in webhook mode")
+	}
+	if (os.Getenv("WEBHOOK_TLS_CERT") == "") != (os.Getenv("WEBHOOK_TLS_KEY") == "") {
+		return errors.New("WEBHOOK_TLS_CERT and WEBHOOK_TLS_KEY must be set together")
+	}
+	return nil
+}
+
 // validateAuthorizedGroups checks if the authorized groups are valid numeric IDs.
 func validateAuthorizedGroups(groups string) error {
 	groupIDs := strings.Split(groups, ",")
@@ -0,0 +1,182 @@
+package db
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Message content is encrypted with envelope encryption: every row gets its own
+// random data key, which encrypts the content and is itself encrypted ("wrapped")
+// with a key from the Keyring. Rotating keys only re-wraps the data keys.
+
+// dataKeySize is the size of data keys and key-encryption keys (AES-256).
+const dataKeySize = 32
+
+// Keyring holds the key-encryption keys by ID. The active key wraps the data keys of
+// new rows; the others are kept to read rows written before a rotation.
+type Keyring struct {
+	active string
+	keys   map[string][]byte
+}
+
+// LoadKeyring reads the keys from ENCRYPTION_KEYS, or from the file named by
+// ENCRYPTION_KEYS_FILE. It returns nil when neither is set, leaving content unencrypted.
+func LoadKeyring() (*Keyring, error) {
+	spec := os.Getenv("ENCRYPTION_KEYS")
+	if path := os.Getenv("ENCRYPTION_KEYS_FILE"); spec == "" && path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading ENCRYPTION_KEYS_FILE: %w", err)
+		}
+		spec = string(data)
+	}
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	return ParseKeyring(spec)
+}
+
+// ParseKeyring parses keys written as "id:base64key", separated by commas or newlines.
+// The first key is the active one. Keys must be 32 bytes long once decoded.
+func ParseKeyring(spec string) (*Keyring, error) {
+	keyring := &Keyring{keys: map[string][]byte{}}
+	entries := strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '\n' })
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid key %q: expected id:base64key", entry)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != dataKeySize {
+			return nil, fmt.Errorf("invalid key %q: expected %d base64-encoded bytes", id, dataKeySize)
+		}
+		if _, exists := keyring.keys[id]; exists {
+			return nil, fmt.Errorf("duplicate key id %q", id)
+		}
+		keyring.keys[id] = key
+		if keyring.active == "" {
+			keyring.active = id
+		}
+	}
+	if keyring.active == "" {
+		return nil, fmt.Errorf("no encryption keys found")
+	}
+	return keyring, nil
+}
+
+// ActiveKeyID returns the ID of the key that wraps new data keys.
+func (k *Keyring) ActiveKeyID() string {
+	return k.active
+}
+
+// newDataKey returns a fresh data key together with its copy wrapped by the active key.
+func (k *Keyring) newDataKey() (dataKey []byte, keyID, wrappedKey string, err error) {
+	dataKey = make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, "", "", err
+	}
+	wrapped, err := seal(k.keys[k.active], dataKey, []byte(k.active))
+	if err != nil {
+		return nil, "", "", err
+	}
+	return dataKey, k.active, encode(wrapped), nil
+}
+
+// rewrap wraps a data key again with the active key.
+func (k *Keyring) rewrap(keyID, wrappedKey string) (newKeyID, newWrappedKey string, err error) {
+	dataKey, err := k.unwrap(keyID, wrappedKey)
+	if err != nil {
+		return "", "", err
+	}
+	wrapped, err := seal(k.keys[k.active], dataKey, []byte(k.active))
+	if err != nil {
+		return "", "", err
+	}
+	return k.active, encode(wrapped), nil
+}
+
+func (k *Keyring) unwrap(keyID, wrappedKey string) ([]byte, error) {
+	key, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown encryption key %q", keyID)
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(wrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := open(key, wrapped, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("unwrapping data key with key %q: %w", keyID, err)
+	}
+	return dataKey, nil
+}
+
+// sealString encrypts a text field with a data key. The additional data binds the
+// ciphertext to its row and column, so it cannot be moved to another one.
+func sealString(dataKey []byte, plaintext, additionalData string) (string, error) {
+	sealed, err := seal(dataKey, []byte(plaintext), []byte(additionalData))
+	if err != nil {
+		return "", err
+	}
+	return encode(sealed), nil
+}
+
+// openString reverses sealString.
+func openString(dataKey []byte, ciphertext, additionalData string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(dataKey, sealed, []byte(additionalData))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// seal encrypts with AES-GCM and prepends the random nonce.
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open reverses seal.
+func open(key, sealed, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encode(data []byte) string {
+	return base64.StdEncoding.EncodeToString(data)
+}
@@ -1,54 +1,46 @@
 package db
 
 import (
-	"database/sql"
+	"context"
 	"log"
 	"os"
-
-	_ "github.com/lib/pq" // PostgreSQL driver
 )
 
-var db *sql.DB
+var store MessageStore
 
-// InitDB initializes the database connection and sets up connection pooling.
+// InitDB initializes the database connection and brings the schema up to date.
 func InitDB() {
-	var err error
-	dbURL := os.Getenv("DATABASE_URL")
-	db, err = sql.Open("postgres", dbURL)
-	if err != nil {
-		log.Fatalf("Error opening database: %v", err)
+	OpenDB()
+
+	if _, err := store.MigrateUp(context.Background()); err != nil {
+		log.Fatalf("Error migrating database: %v", err)
 	}
+}
 
-	if err = db.Ping(); err != nil {
-		log.Fatalf("Error connecting to the database: %v", err)
+// OpenDB opens the store selected by DATABASE_URL without touching the schema.
+func OpenDB() {
+	keys, err := LoadKeyring()
+	if err != nil {
+		log.Fatalf("Error loading encryption keys: %v", err)
 	}
 
-	query := `
-CREATE TABLE IF NOT EXISTS messages (
-    message_id BIGINT PRIMARY KEY,
-    timestamp TIMESTAMP NOT NULL,
-    name TEXT,
-    last_name TEXT,
-    username TEXT,
-    group_id BIGINT,
-    user_id BIGINT,
-    content TEXT
-);
-`
-	_, err = db.Exec(query)
+	store, err = Open(os.Getenv("DATABASE_URL"), keys)
 	if err != nil {
-		log.Fatalf("Error creating messages table: %v", err)
+		log.Fatalf("Error opening database: %v", err)
 	}
 }
 
-// GetDB returns the database connection.
-func GetDB() *sql.DB {
-	return db
+// GetStore returns the message store.
+func GetStore() MessageStore {
+	return store
 }
 
 // CloseDB closes the database connection.
 func CloseDB() {
-	if err := db.Close(); err != nil {
+	if store == nil {
+		return
+	}
+	if err := store.Close(); err != nil {
 		log.Fatalf("Error closing the database: %v", err)
 	}
 	log.Println("Database connection closed.")
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// GetDigestSchedule returns the digest schedule of a group, or nil if it has none.
+func (s *sqlStore) GetDigestSchedule(ctx context.Context, groupID int64) (*DigestSchedule, error) {
+	query := `SELECT group_id, frequency, weekday, hour, minute, timezone, last_run, next_run
+		  FROM digest_schedules WHERE group_id = $1`
+	schedules, err := s.queryDigestSchedules(ctx, query, groupID)
+	if err != nil || len(schedules) == 0 {
+		return nil, err
+	}
+	return &schedules[0], nil
+}
+
+// GetDueDigestSchedules returns the schedules whose next run is at or before now.
+func (s *sqlStore) GetDueDigestSchedules(ctx context.Context, now time.Time) ([]DigestSchedule, error) {
+	query := `SELECT group_id, frequency, weekday, hour, minute, timezone, last_run, next_run
+		  FROM digest_schedules WHERE next_run <= $1 ORDER BY next_run ASC`
+	return s.queryDigestSchedules(ctx, query, s.bindTime(now))
+}
+
+// SaveDigestSchedule creates or replaces the digest schedule of a group.
+// The last run is kept so the next digest still starts where the previous one ended.
+func (s *sqlStore) SaveDigestSchedule(ctx context.Context, schedule DigestSchedule) error {
+	query := `INSERT INTO digest_schedules (group_id, frequency, weekday, hour, minute, timezone, next_run)
+              VALUES ($1, $2, $3, $4, $5, $6, $7)
+              ON CONFLICT (group_id) DO UPDATE SET
+                  frequency = EXCLUDED.frequency,
+                  weekday = EXCLUDED.weekday,
+                  hour = EXCLUDED.hour,
+                  minute = EXCLUDED.minute,
+                  timezone = EXCLUDED.timezone,
+                  next_run = EXCLUDED.next_run`
+	_, err := s.exec(ctx, query,
+		schedule.GroupID,
+		schedule.Frequency,
+		int(schedule.Weekday),
+		schedule.Hour,
+		schedule.Minute,
+		schedule.Timezone,
+		s.bindTime(schedule.NextRun),
+	)
+	return err
+}
+
+// AdvanceDigestSchedule moves the next run of a schedule, leaving its last run alone
+// until the digest has been produced.
+func (s *sqlStore) AdvanceDigestSchedule(ctx context.Context, groupID int64, nextRun time.Time) error {
+	_, err := s.exec(ctx, `UPDATE digest_schedules SET next_run = $2 WHERE group_id = $1`, groupID, s.bindTime(nextRun))
+	return err
+}
+
+// MarkDigestRun records that a digest covering everything up to lastRun was produced.
+func (s *sqlStore) MarkDigestRun(ctx context.Context, groupID int64, lastRun time.Time) error {
+	_, err := s.exec(ctx, `UPDATE digest_schedules SET last_run = $2 WHERE group_id = $1`, groupID, s.bindTime(lastRun))
+	return err
+}
+
+// DeleteDigestSchedule turns off the digest of a group.
+func (s *sqlStore) DeleteDigestSchedule(ctx context.Context, groupID int64) error {
+	_, err := s.exec(ctx, `DELETE FROM digest_schedules WHERE group_id = $1`, groupID)
+	return err
+}
+
+func (s *sqlStore) queryDigestSchedules(ctx context.Context, query string, args ...interface{}) ([]DigestSchedule, error) {
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []DigestSchedule
+	for rows.Next() {
+		var schedule DigestSchedule
+		var weekday int
+		var lastRun sql.NullTime
+		if err := rows.Scan(&schedule.GroupID, &schedule.Frequency, &weekday, &schedule.Hour, &schedule.Minute, &schedule.Timezone, &lastRun, &schedule.NextRun); err != nil {
+			return nil, err
+		}
+		schedule.Weekday = time.Weekday(weekday)
+		if lastRun.Valid {
+			schedule.LastRun = lastRun.Time
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, rows.Err()
+}
@@ -0,0 +1,276 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// sealedText is the stored form of the text fields of a message: plaintext with NULL
+// key columns, or ciphertext sharing one data key when the store has keys.
+type sealedText struct {
+	content, caption, transcript sql.NullString
+	keyID, wrappedKey            sql.NullString
+}
+
+// currentRevision stands for the text of a message in the messages table, as opposed
+// to one of its revisions in message_revisions.
+const currentRevision = -1
+
+// sealText encrypts the text fields of a message, or of one of its revisions, when the
+// store has keys, reusing the data key of the row when keyID is set so that its other
+// fields stay readable. Empty captions and transcripts are stored as NULL.
+func (s *sqlStore) sealText(groupID, messageID, revision int64, content, caption, transcript string, keyID, wrappedKey sql.NullString) (sealedText, error) {
+	sealed := sealedText{
+		content:    sql.NullString{String: content, Valid: true},
+		caption:    nullString(caption),
+		transcript: nullString(transcript),
+	}
+	if s.keys == nil {
+		if keyID.Valid {
+			return sealed, fmt.Errorf("message %d is encrypted but no encryption keys are configured", messageID)
+		}
+		return sealed, nil
+	}
+
+	var dataKey []byte
+	var err error
+	if keyID.Valid {
+		dataKey, err = s.keys.unwrap(keyID.String, wrappedKey.String)
+		sealed.keyID, sealed.wrappedKey = keyID, wrappedKey
+	} else {
+		var id, wrapped string
+		dataKey, id, wrapped, err = s.keys.newDataKey()
+		sealed.keyID, sealed.wrappedKey = nullString(id), nullString(wrapped)
+	}
+	if err != nil {
+		return sealed, fmt.Errorf("encrypting message %d: %w", messageID, err)
+	}
+
+	for _, field := range []struct {
+		value  *sql.NullString
+		column string
+	}{
+		{&sealed.content, "content"},
+		{&sealed.caption, "caption"},
+		{&sealed.transcript, "transcript"},
+	} {
+		if !field.value.Valid {
+			continue
+		}
+		if field.value.String, err = sealString(dataKey, field.value.String, textAAD(groupID, messageID, revision, field.column)); err != nil {
+			return sealed, fmt.Errorf("encrypting %s of message %d: %w", field.column, messageID, err)
+		}
+	}
+	return sealed, nil
+}
+
+// openText returns the plaintext of the text fields read from a row.
+func (s *sqlStore) openText(groupID, messageID, revision int64, sealed sealedText) (content, caption, transcript string, err error) {
+	if !sealed.keyID.Valid {
+		return sealed.content.String, sealed.caption.String, sealed.transcript.String, nil
+	}
+	if s.keys == nil {
+		return "", "", "", fmt.Errorf("message %d is encrypted but no encryption keys are configured", messageID)
+	}
+
+	dataKey, err := s.keys.unwrap(sealed.keyID.String, sealed.wrappedKey.String)
+	if err != nil {
+		return "", "", "", fmt.Errorf("message %d: %w", messageID, err)
+	}
+
+	plaintexts := make([]string, 3)
+	for i, field := range []struct {
+		value  sql.NullString
+		column string
+	}{
+		{sealed.content, "content"},
+		{sealed.caption, "caption"},
+		{sealed.transcript, "transcript"},
+	} {
+		if !field.value.Valid {
+			continue
+		}
+		if plaintexts[i], err = openString(dataKey, field.value.String, textAAD(groupID, messageID, revision, field.column)); err != nil {
+			return "", "", "", fmt.Errorf("decrypting %s of message %d: %w", field.column, messageID, err)
+		}
+	}
+	return plaintexts[0], plaintexts[1], plaintexts[2], nil
+}
+
+// textAAD ties an encrypted field to its message, revision and column, so that it
+// cannot be moved to another row: "group/message", then "/r<revision>" for revisions
+// and "/<column>" for every column but content.
+func textAAD(groupID, messageID, revision int64, column string) string {
+	aad := fmt.Sprintf("%d/%d", groupID, messageID)
+	if revision != currentRevision {
+		aad += fmt.Sprintf("/r%d", revision)
+	}
+	if column != "content" {
+		aad += "/" + column
+	}
+	return aad
+}
+
+// encryptedTable describes a table holding encrypted message text.
+type encryptedTable struct {
+	name string
+	// revision selects the revision of a row, "-1" (currentRevision) for tables without one.
+	revision string
+	// transcript tells whether the table has a transcript column.
+	transcript bool
+}
+
+var encryptedTables = []encryptedTable{
+	{name: "messages", revision: "-1", transcript: true},
+	{name: "message_revisions", revision: "revision"},
+}
+
+// Rotation counts the rows rewritten by RotateKeys or DecryptAll.
+type Rotation struct {
+	// Updated is how many rows were rewritten.
+	Updated int64
+	// Skipped is how many times a row changed between being read and rewritten. Such
+	// rows are left as they were and read again in a later batch.
+	Skipped int64
+}
+
+// staleRow is a row of an encryptedTable being rotated.
+type staleRow struct {
+	groupID, messageID, revision int64
+	text                         sealedText
+}
+
+// RotateKeys moves every row to the active key: data keys wrapped by an older key are
+// re-wrapped, and text still stored in plaintext is encrypted. Rows are processed
+// in batches of batchSize.
+func (s *sqlStore) RotateKeys(ctx context.Context, batchSize int) (Rotation, error) {
+	return s.rewriteRows(ctx, batchSize, false)
+}
+
+// DecryptAll stores the text of every encrypted row back in plaintext, so that
+// encryption can be turned off or its migration reverted. Rows are processed in
+// batches of batchSize.
+func (s *sqlStore) DecryptAll(ctx context.Context, batchSize int) (Rotation, error) {
+	return s.rewriteRows(ctx, batchSize, true)
+}
+
+// rewriteRows rotates, or with decrypt set decrypts, the rows of every encrypted table.
+func (s *sqlStore) rewriteRows(ctx context.Context, batchSize int, decrypt bool) (Rotation, error) {
+	var rotation Rotation
+	if s.keys == nil {
+		return rotation, fmt.Errorf("no encryption keys configured")
+	}
+
+	for _, table := range encryptedTables {
+		for {
+			rows, err := s.staleRows(ctx, table, batchSize, decrypt)
+			if err != nil {
+				return rotation, err
+			}
+			if len(rows) == 0 {
+				break
+			}
+			progressed := false
+			for _, row := range rows {
+				updated, err := s.rotateRow(ctx, table, row, decrypt)
+				if err != nil {
+					return rotation, err
+				}
+				if updated {
+					rotation.Updated++
+					progressed = true
+				} else {
+					rotation.Skipped++
+				}
+			}
+			// Skipped rows are still stale and read again, unless they keep changing.
+			if !progressed {
+				return rotation, fmt.Errorf("%s: every row of a batch changed while being rewritten", table.name)
+			}
+		}
+	}
+	return rotation, nil
+}
+
+// staleRows returns up to limit rows of table not encrypted under the active key, or
+// with decrypt set, up to limit encrypted rows.
+func (s *sqlStore) staleRows(ctx context.Context, table encryptedTable, limit int, decrypt bool) ([]staleRow, error) {
+	transcript := "NULL"
+	if table.transcript {
+		transcript = "transcript"
+	}
+	filter, args := "(key_id IS NULL OR key_id <> $2)", []interface{}{limit, s.keys.ActiveKeyID()}
+	if decrypt {
+		filter, args = "key_id IS NOT NULL", args[:1]
+	}
+	query := fmt.Sprintf(`SELECT group_id, message_id, %s, content, caption, %s, key_id, wrapped_key FROM %s
+              WHERE content IS NOT NULL AND %s LIMIT $1`, table.revision, transcript, table.name, filter)
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stale []staleRow
+	for rows.Next() {
+		var row staleRow
+		if err := rows.Scan(&row.groupID, &row.messageID, &row.revision, &row.text.content, &row.text.caption, &row.text.transcript, &row.text.keyID, &row.text.wrappedKey); err != nil {
+			return nil, err
+		}
+		stale = append(stale, row)
+	}
+	return stale, rows.Err()
+}
+
+// rotateRow re-wraps the data key of a row, or encrypts its text if it has none. With
+// decrypt set, it stores the text in plaintext instead. It reports whether the row was
+// updated: a row edited since it was read is left alone, rather than overwritten with
+// the text read before the edit.
+func (s *sqlStore) rotateRow(ctx context.Context, table encryptedTable, row staleRow, decrypt bool) (bool, error) {
+	text := row.text
+	if decrypt {
+		content, caption, transcript, err := s.openText(row.groupID, row.messageID, row.revision, text)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", table.name, err)
+		}
+		text = sealedText{
+			content:    sql.NullString{String: content, Valid: true},
+			caption:    nullString(caption),
+			transcript: nullString(transcript),
+		}
+	} else if text.keyID.Valid {
+		keyID, wrappedKey, err := s.keys.rewrap(text.keyID.String, text.wrappedKey.String)
+		if err != nil {
+			return false, fmt.Errorf("%s message %d: %w", table.name, row.messageID, err)
+		}
+		text.keyID, text.wrappedKey = nullString(keyID), nullString(wrappedKey)
+	} else {
+		var err error
+		text, err = s.sealText(row.groupID, row.messageID, row.revision, text.content.String, text.caption.String, text.transcript.String, text.keyID, text.wrappedKey)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	old := row.text
+	args := []interface{}{row.groupID, row.messageID, row.revision,
+		text.content, text.caption, text.keyID, text.wrappedKey,
+		old.content, old.caption, old.keyID, old.wrappedKey}
+	columns := "content = $4, caption = $5, key_id = $6, wrapped_key = $7"
+	unchanged := `content IS NOT DISTINCT FROM $8 AND caption IS NOT DISTINCT FROM $9
+              AND key_id IS NOT DISTINCT FROM $10 AND wrapped_key IS NOT DISTINCT FROM $11`
+	if table.transcript {
+		args = append(args, text.transcript, old.transcript)
+		columns += ", transcript = $12"
+		unchanged += " AND transcript IS NOT DISTINCT FROM $13"
+	}
+	query := fmt.Sprintf(`UPDATE %s SET %s WHERE group_id = $1 AND message_id = $2 AND %s = $3 AND %s`,
+		table.name, columns, table.revision, unchanged)
+	result, err := s.exec(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	updated, err := result.RowsAffected()
+	return updated > 0, err
+}
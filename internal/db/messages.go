@@ -1,41 +1,248 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 )
 
 // LogMessage inserts a new message into the database.
-func LogMessage(db *sql.DB, message Message) error {
-	query := `INSERT INTO messages (message_id, timestamp, name, last_name, username, group_id, user_id, content)
-              VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
-	_, err := db.Exec(query,
+func (s *sqlStore) LogMessage(ctx context.Context, message Message) error {
+	text, err := s.sealText(message.GroupID, message.MessageID, currentRevision, message.Content, message.Caption, message.Transcript, sql.NullString{}, sql.NullString{})
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO messages (message_id, timestamp, name, last_name, username, group_id, user_id, content,
+                  message_type, caption, reply_to_message_id, forward_from, file_id, transcript, key_id, wrapped_key)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+              ON CONFLICT (group_id, message_id) DO NOTHING`
+	_, err = s.exec(ctx, query,
 		message.MessageID,
-		message.Timestamp,
+		s.bindTime(message.Timestamp),
 		message.Name,
 		message.LastName,
 		message.Username,
 		message.GroupID,
 		message.UserID,
-		message.Content,
+		text.content,
+		nullString(message.Type),
+		text.caption,
+		sql.NullInt64{Int64: message.ReplyToMessageID, Valid: message.ReplyToMessageID != 0},
+		nullString(message.ForwardFrom),
+		nullString(message.FileID),
+		text.transcript,
+		text.keyID,
+		text.wrappedKey,
 	)
 
 	return err
 }
 
-// GetMessages retrieves messages from the database based on message ID and group ID.
-func GetMessages(db *sql.DB, messageID int64, groupID int64) ([]Message, error) {
-	firstMessageTimestamp, err := getMessageTimestamp(db, messageID, groupID)
+// LogEdit stores the new text of an edited message and keeps the previous versions
+// in message_revisions. Edits of messages that were never logged are stored as new messages.
+func (s *sqlStore) LogEdit(ctx context.Context, message Message, editedAt time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	defer tx.Rollback()
+
+	// Encrypt with the data key of the row, which its transcript is encrypted with. A
+	// row logged in plaintext gets a new data key, so its transcript is sealed with it too.
+	var current sealedText
+	err = tx.QueryRowContext(ctx, s.rebind(`SELECT content, caption, transcript, key_id, wrapped_key FROM messages WHERE group_id = $1 AND message_id = $2`),
+		message.GroupID, message.MessageID).Scan(&current.content, &current.caption, &current.transcript, &current.keyID, &current.wrappedKey)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	keyID, wrappedKey := current.keyID, current.wrappedKey
+	plainTranscript := ""
+	if !keyID.Valid {
+		plainTranscript = current.transcript.String
+	}
+	text, err := s.sealText(message.GroupID, message.MessageID, currentRevision, message.Content, message.Caption, plainTranscript, keyID, wrappedKey)
+	if err != nil {
+		return err
+	}
+	if keyID.Valid {
+		text.transcript = current.transcript
+	}
+
+	// Revisions are sealed for their own revision number, so that they cannot be
+	// swapped with one another or with the current text.
+	var last sql.NullInt64
+	err = tx.QueryRowContext(ctx, s.rebind(`SELECT MAX(revision) FROM message_revisions WHERE group_id = $1 AND message_id = $2`),
+		message.GroupID, message.MessageID).Scan(&last)
+	if err != nil {
+		return err
+	}
+	if !last.Valid {
+		// Keep the text as first sent the first time a message is edited.
+		content, caption, _, err := s.openText(message.GroupID, message.MessageID, currentRevision, current)
+		if err != nil {
+			return err
+		}
+		first, err := s.sealText(message.GroupID, message.MessageID, 0, content, caption, "", text.keyID, text.wrappedKey)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, s.rebind(`INSERT INTO message_revisions (group_id, message_id, revision, edited_at, content, caption, key_id, wrapped_key)
+              SELECT group_id, message_id, 0, timestamp, $3, $4, $5, $6 FROM messages
+              WHERE group_id = $1 AND message_id = $2`),
+			message.GroupID, message.MessageID, first.content, first.caption, first.keyID, first.wrappedKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Media can be replaced by an edit too.
+	result, err := tx.ExecContext(ctx, s.rebind(`UPDATE messages SET content = $3, caption = $4, transcript = $5, key_id = $6,
+                  wrapped_key = $7, message_type = $8, file_id = $9, edited_at = $10
+              WHERE group_id = $1 AND message_id = $2`),
+		message.GroupID, message.MessageID, text.content, text.caption, text.transcript, text.keyID, text.wrappedKey,
+		nullString(message.Type), nullString(message.FileID), s.bindTime(editedAt))
+	if err != nil {
+		return err
+	}
+	if updated, err := result.RowsAffected(); err != nil {
+		return err
+	} else if updated == 0 {
+		if err := tx.Rollback(); err != nil {
+			return err
+		}
+		return s.LogMessage(ctx, message)
+	}
+
+	revision := last.Int64 + 1
+	edit, err := s.sealText(message.GroupID, message.MessageID, revision, message.Content, message.Caption, "", text.keyID, text.wrappedKey)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, s.rebind(`INSERT INTO message_revisions (group_id, message_id, revision, edited_at, content, caption, key_id, wrapped_key)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`),
+		message.GroupID, message.MessageID, revision, s.bindTime(editedAt), edit.content, edit.caption, edit.keyID, edit.wrappedKey)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetTranscript stores the transcript of a voice, audio or video note message.
+func (s *sqlStore) SetTranscript(ctx context.Context, groupID, messageID int64, transcript string) error {
+	for {
+		var current sealedText
+		err := s.queryRow(ctx, `SELECT content, caption, key_id, wrapped_key FROM messages WHERE group_id = $1 AND message_id = $2`,
+			groupID, messageID).Scan(&current.content, &current.caption, &current.keyID, &current.wrappedKey)
+		if err == sql.ErrNoRows {
+			return nil // Deleted while it was being transcribed
+		}
+		if err != nil {
+			return err
+		}
+
+		var result sql.Result
+		if current.keyID.Valid {
+			text, err := s.sealText(groupID, messageID, currentRevision, "", "", transcript, current.keyID, current.wrappedKey)
+			if err != nil {
+				return err
+			}
+			result, err = s.exec(ctx, `UPDATE messages SET transcript = $3 WHERE group_id = $1 AND message_id = $2 AND key_id = $4`,
+				groupID, messageID, text.transcript, current.keyID)
+			if err != nil {
+				return err
+			}
+		} else {
+			// A row logged in plaintext gets a new data key, like in LogEdit, so its
+			// content and caption are sealed along with the transcript.
+			text, err := s.sealText(groupID, messageID, currentRevision, current.content.String, current.caption.String, transcript, current.keyID, current.wrappedKey)
+			if err != nil {
+				return err
+			}
+			result, err = s.exec(ctx, `UPDATE messages SET content = $3, caption = $4, transcript = $5, key_id = $6, wrapped_key = $7
+                  WHERE group_id = $1 AND message_id = $2 AND key_id IS NULL`,
+				groupID, messageID, text.content, text.caption, text.transcript, text.keyID, text.wrappedKey)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Zero rows means an edit or a key rotation sealed the row with another key in
+		// the meantime, so the transcript is sealed again for the new key.
+		if updated, err := result.RowsAffected(); err != nil || updated > 0 {
+			return err
+		}
+	}
+}
+
+// MaxMessages caps how many messages a query without a Limit returns: the most recent
+// ones of the range are kept.
+const MaxMessages = 2000
+
+// GetMessages retrieves the messages of a group selected by the query, oldest first.
+func (s *sqlStore) GetMessages(ctx context.Context, groupID int64, q Query) ([]Message, error) {
+	since, until := q.Since, q.Until
+	if q.AnchorMessageID != 0 {
+		anchorTimestamp, err := s.getMessageTimestamp(ctx, q.AnchorMessageID, groupID)
+		if err != nil {
+			return nil, err
+		}
+		if anchorTimestamp == nil {
+			return nil, nil // Anchor message was never logged
+		}
+		since = *anchorTimestamp
+		if q.Window > 0 {
+			until = since.Add(q.Window)
+		}
+	}
+	if q.EndMessageID != 0 {
+		endTimestamp, err := s.getMessageTimestamp(ctx, q.EndMessageID, groupID)
+		if err != nil {
+			return nil, err
+		}
+		if endTimestamp == nil {
+			return nil, nil // End message was never logged
+		}
+		until = *endTimestamp
+		if until.Before(since) {
+			since, until = until, since
+		}
+	}
+
+	// Windows end right before until, so consecutive ones do not overlap; a range
+	// ending at a message includes it.
+	untilOp := "<"
+	if q.EndMessageID != 0 {
+		untilOp = "<="
+	}
+
+	conditions := []string{"group_id = $1"}
+	args := []interface{}{groupID}
+	if !since.IsZero() {
+		args = append(args, s.bindTime(since))
+		conditions = append(conditions, fmt.Sprintf("timestamp >= $%d", len(args)))
+	}
+	if !until.IsZero() {
+		args = append(args, s.bindTime(until))
+		conditions = append(conditions, fmt.Sprintf("timestamp %s $%d", untilOp, len(args)))
 	}
 
-	query := `SELECT message_id, timestamp, name, last_name, username, group_id, user_id, content
+	limit := MaxMessages
+	if q.Limit > 0 {
+		limit = q.Limit
+	}
+
+	// Read newest first, so the limit keeps the most recent messages.
+	query := fmt.Sprintf(`SELECT message_id, timestamp, name, last_name, username, group_id, user_id, content,
+		      message_type, caption, reply_to_message_id, forward_from, file_id, transcript, key_id, wrapped_key
 		  FROM messages
-		  WHERE group_id = $1 AND timestamp BETWEEN $2 AND ($2 + interval '30 minutes')
-		  ORDER BY timestamp ASC LIMIT 2000`
+		  WHERE %s
+		  ORDER BY timestamp DESC, message_id DESC LIMIT %d`, strings.Join(conditions, " AND "), limit)
 
-	rows, err := db.Query(query, groupID, firstMessageTimestamp)
+	rows, err := s.query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -44,17 +251,36 @@ func GetMessages(db *sql.DB, messageID int64, groupID int64) ([]Message, error)
 	var messages []Message
 	for rows.Next() {
 		var msg Message
-		if err := rows.Scan(&msg.MessageID, &msg.Timestamp, &msg.Name, &msg.LastName, &msg.Username, &msg.GroupID, &msg.UserID, &msg.Content); err != nil {
+		var text sealedText
+		var name, lastName, username, messageType, forwardFrom, fileID sql.NullString
+		var userID, replyTo sql.NullInt64
+		if err := rows.Scan(&msg.MessageID, &msg.Timestamp, &name, &lastName, &username, &msg.GroupID, &userID, &text.content,
+			&messageType, &text.caption, &replyTo, &forwardFrom, &fileID, &text.transcript, &text.keyID, &text.wrappedKey); err != nil {
+			return nil, err
+		}
+		msg.Timestamp = s.scanTime(msg.Timestamp)
+		msg.Name, msg.LastName, msg.Username = name.String, lastName.String, username.String
+		msg.UserID = userID.Int64
+		msg.Type, msg.ReplyToMessageID, msg.ForwardFrom, msg.FileID = messageType.String, replyTo.Int64, forwardFrom.String, fileID.String
+		if msg.Type == "" {
+			msg.Type = MessageText
+		}
+		if msg.Content, msg.Caption, msg.Transcript, err = s.openText(msg.GroupID, msg.MessageID, currentRevision, text); err != nil {
 			return nil, err
 		}
 		messages = append(messages, msg)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	slices.Reverse(messages)
 	return messages, nil
 }
 
-func getMessageTimestamp(db *sql.DB, messageID int64, groupID int64) (*time.Time, error) {
+func (s *sqlStore) getMessageTimestamp(ctx context.Context, messageID int64, groupID int64) (*time.Time, error) {
 	query := `SELECT timestamp FROM messages WHERE message_id = $1 AND group_id = $2`
-	row := db.QueryRow(query, messageID, groupID)
+	row := s.queryRow(ctx, query, messageID, groupID)
 	var timestamp time.Time
 	if err := row.Scan(&timestamp); err != nil {
 		if err == sql.ErrNoRows {
@@ -62,5 +288,64 @@ func getMessageTimestamp(db *sql.DB, messageID int64, groupID int64) (*time.Time
 		}
 		return nil, err
 	}
+	timestamp = s.scanTime(timestamp)
 	return &timestamp, nil
 }
+
+// GetGroupIDs returns every group with logged messages.
+func (s *sqlStore) GetGroupIDs(ctx context.Context) ([]int64, error) {
+	rows, err := s.query(ctx, `SELECT DISTINCT group_id FROM messages`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groupIDs []int64
+	for rows.Next() {
+		var groupID int64
+		if err := rows.Scan(&groupID); err != nil {
+			return nil, err
+		}
+		groupIDs = append(groupIDs, groupID)
+	}
+	return groupIDs, rows.Err()
+}
+
+// DeleteMessage removes a single message and its revisions.
+func (s *sqlStore) DeleteMessage(ctx context.Context, groupID, messageID int64) error {
+	_, err := s.exec(ctx, `DELETE FROM messages WHERE group_id = $1 AND message_id = $2`, groupID, messageID)
+	return err
+}
+
+// DeleteMessages removes the messages of a group sent between since and until;
+// zero times leave that side of the range open. It returns how many rows were deleted.
+func (s *sqlStore) DeleteMessages(ctx context.Context, groupID int64, since, until time.Time) (int64, error) {
+	conditions := []string{"group_id = $1"}
+	args := []interface{}{groupID}
+	if !since.IsZero() {
+		args = append(args, s.bindTime(since))
+		conditions = append(conditions, fmt.Sprintf("timestamp >= $%d", len(args)))
+	}
+	if !until.IsZero() {
+		args = append(args, s.bindTime(until))
+		conditions = append(conditions, fmt.Sprintf("timestamp <= $%d", len(args)))
+	}
+
+	result, err := s.exec(ctx, `DELETE FROM messages WHERE `+strings.Join(conditions, " AND "), args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// PurgeMessages deletes at most limit messages of a group sent before the given time,
+// so large purges can run in short batches. It returns how many rows were deleted.
+func (s *sqlStore) PurgeMessages(ctx context.Context, groupID int64, before time.Time, limit int) (int64, error) {
+	query := `DELETE FROM messages WHERE group_id = $1 AND message_id IN (
+                  SELECT message_id FROM messages WHERE group_id = $1 AND timestamp < $2 LIMIT $3)`
+	result, err := s.exec(ctx, query, groupID, s.bindTime(before), limit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,245 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/postgres/*.sql migrations/sqlite/*.sql
+var migrationFiles embed.FS
+
+// Migration is a schema change shipped with the binary as a pair of
+// migrations/<dialect>/NNNN_name.up.sql and NNNN_name.down.sql files.
+type Migration struct {
+	Version int
+	Name    string
+	up      string
+	down    string
+}
+
+// MigrationState reports whether a migration has been applied.
+type MigrationState struct {
+	Migration
+	AppliedAt time.Time // zero when pending
+}
+
+// loadMigrations returns the embedded migrations of a dialect ordered by version.
+func loadMigrations(dialect string) ([]Migration, error) {
+	dir := "migrations/" + dialect
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		file := entry.Name()
+		base, direction, ok := strings.Cut(strings.TrimSuffix(file, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration file name: %s", file)
+		}
+		prefix, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", file)
+		}
+
+		content, err := fs.ReadFile(migrationFiles, dir+"/"+file)
+		if err != nil {
+			return nil, err
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.up = string(content)
+		} else {
+			m.down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// MigrateUp applies every pending migration in order and returns how many were applied.
+func (s *sqlStore) MigrateUp(ctx context.Context) (int, error) {
+	applied := 0
+	err := s.withMigrationLock(ctx, func(conn *sql.Conn, done map[int]time.Time) error {
+		migrations, err := loadMigrations(s.dialect.name)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			if _, ok := done[m.Version]; ok {
+				continue
+			}
+			log.Printf("Applying migration %04d_%s", m.Version, m.Name)
+			bookkeeping := s.rebind(`INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)`)
+			if err := runMigration(ctx, conn, m.up, bookkeeping, m.Version, m.Name, time.Now().UTC()); err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+			}
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// MigrateDown reverts the latest steps applied migrations and returns how many were reverted.
+func (s *sqlStore) MigrateDown(ctx context.Context, steps int) (int, error) {
+	reverted := 0
+	err := s.withMigrationLock(ctx, func(conn *sql.Conn, done map[int]time.Time) error {
+		migrations, err := loadMigrations(s.dialect.name)
+		if err != nil {
+			return err
+		}
+		var pending []Migration
+		for i := len(migrations) - 1; i >= 0 && len(pending) < steps; i-- {
+			if _, ok := done[migrations[i].Version]; ok {
+				pending = append(pending, migrations[i])
+			}
+		}
+
+		// Check everything first, so a refused migration leaves the schema untouched.
+		for _, m := range pending {
+			if check := downChecks[m.Name]; check != nil {
+				if err := check(ctx, conn); err != nil {
+					return fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+				}
+			}
+		}
+
+		for _, m := range pending {
+			if m.down == "" {
+				return fmt.Errorf("migration %04d_%s cannot be reverted: no down file", m.Version, m.Name)
+			}
+			log.Printf("Reverting migration %04d_%s", m.Version, m.Name)
+			bookkeeping := s.rebind(`DELETE FROM schema_migrations WHERE version = $1`)
+			if err := runMigration(ctx, conn, m.down, bookkeeping, m.Version); err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+			}
+			reverted++
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// MigrationStatus lists every known migration and when it was applied.
+func (s *sqlStore) MigrationStatus(ctx context.Context) ([]MigrationState, error) {
+	var states []MigrationState
+	err := s.withMigrationLock(ctx, func(_ *sql.Conn, done map[int]time.Time) error {
+		migrations, err := loadMigrations(s.dialect.name)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			states = append(states, MigrationState{Migration: m, AppliedAt: done[m.Version]})
+		}
+		return nil
+	})
+	return states, err
+}
+
+// withMigrationLock runs fn on a single connection holding the migration lock,
+// passing the versions already recorded in schema_migrations.
+func (s *sqlStore) withMigrationLock(ctx context.Context, fn func(conn *sql.Conn, done map[int]time.Time) error) error {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if s.dialect.lock != nil {
+		unlock, err := s.dialect.lock(ctx, conn)
+		if err != nil {
+			return fmt.Errorf("acquiring migration lock: %w", err)
+		}
+		defer unlock()
+	}
+
+	if _, err := conn.ExecContext(ctx, s.dialect.migrationsTable); err != nil {
+		return fmt.Errorf("creating schema_migrations table: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return err
+	}
+	done := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		done[version] = appliedAt
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return fn(conn, done)
+}
+
+// runMigration executes a migration script and its bookkeeping statement in one transaction.
+func runMigration(ctx context.Context, conn *sql.Conn, script, bookkeeping string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, bookkeeping, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// migrationCheck refuses a migration that would lose data.
+type migrationCheck func(ctx context.Context, conn *sql.Conn) error
+
+// downChecks guard the down scripts of migrations, by migration name.
+var downChecks = map[string]migrationCheck{
+	// Dropping the key columns would leave encrypted text unreadable for good.
+	"encrypt_message_content": refuseEncryptedRows,
+}
+
+func refuseEncryptedRows(ctx context.Context, conn *sql.Conn) error {
+	for _, table := range []string{"messages", "message_revisions"} {
+		var count int64
+		if err := conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+table+` WHERE key_id IS NOT NULL`).Scan(&count); err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("%s holds %d encrypted row(s) that would become unreadable: run the decrypt command with the current ENCRYPTION_KEYS first", table, count)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS messages (
+    message_id BIGINT PRIMARY KEY,
+    timestamp TIMESTAMP NOT NULL,
+    name TEXT,
+    last_name TEXT,
+    username TEXT,
+    group_id BIGINT,
+    user_id BIGINT,
+    content TEXT
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS digest_schedules (
+    group_id BIGINT PRIMARY KEY,
+    frequency TEXT NOT NULL,
+    weekday INT NOT NULL DEFAULT 0,
+    hour INT NOT NULL,
+    minute INT NOT NULL,
+    timezone TEXT NOT NULL,
+    last_run TIMESTAMPTZ,
+    next_run TIMESTAMPTZ NOT NULL
+);
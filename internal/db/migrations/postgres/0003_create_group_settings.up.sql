@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS group_settings (
+    group_id BIGINT PRIMARY KEY,
+    lang TEXT,
+    provider TEXT,
+    window_minutes INT,
+    style TEXT,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS messages_group_id_timestamp_idx;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS messages_group_id_timestamp_idx ON messages (group_id, timestamp);
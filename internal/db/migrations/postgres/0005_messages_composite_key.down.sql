@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS message_revisions;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS edited_at;
+
+-- Message IDs shared by several groups cannot coexist under the old key; keep one of them.
+DELETE FROM messages a USING messages b
+WHERE a.message_id = b.message_id AND a.group_id > b.group_id;
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_pkey;
+ALTER TABLE messages ADD PRIMARY KEY (message_id);
+ALTER TABLE messages ALTER COLUMN group_id DROP NOT NULL;
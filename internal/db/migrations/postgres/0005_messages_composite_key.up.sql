@@ -0,0 +1,18 @@
+-- Telegram message IDs are only unique per chat.
+DELETE FROM messages WHERE group_id IS NULL;
+ALTER TABLE messages ALTER COLUMN group_id SET NOT NULL;
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_pkey;
+ALTER TABLE messages ADD PRIMARY KEY (group_id, message_id);
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP;
+
+-- Every version of an edited message; revision 0 is the text as first sent.
+CREATE TABLE IF NOT EXISTS message_revisions (
+    group_id BIGINT NOT NULL,
+    message_id BIGINT NOT NULL,
+    revision INT NOT NULL,
+    edited_at TIMESTAMP NOT NULL,
+    content TEXT,
+    PRIMARY KEY (group_id, message_id, revision),
+    FOREIGN KEY (group_id, message_id) REFERENCES messages (group_id, message_id) ON DELETE CASCADE
+);
@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS user_optouts (
+    user_id BIGINT PRIMARY KEY,
+    opted_out_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,5 @@
+-- Refused while encrypted rows exist (see downChecks): run the decrypt command first.
+ALTER TABLE message_revisions DROP COLUMN IF EXISTS wrapped_key;
+ALTER TABLE message_revisions DROP COLUMN IF EXISTS key_id;
+ALTER TABLE messages DROP COLUMN IF EXISTS wrapped_key;
+ALTER TABLE messages DROP COLUMN IF EXISTS key_id;
@@ -0,0 +1,6 @@
+ALTER TABLE message_revisions DROP COLUMN IF EXISTS caption;
+ALTER TABLE messages DROP COLUMN IF EXISTS file_id;
+ALTER TABLE messages DROP COLUMN IF EXISTS forward_from;
+ALTER TABLE messages DROP COLUMN IF EXISTS reply_to_message_id;
+ALTER TABLE messages DROP COLUMN IF EXISTS caption;
+ALTER TABLE messages DROP COLUMN IF EXISTS message_type;
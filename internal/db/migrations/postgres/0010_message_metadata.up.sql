@@ -0,0 +1,8 @@
+-- What kind of message was logged and how it relates to others. NULL message_type
+-- marks text messages logged before types were recorded.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS message_type TEXT;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS caption TEXT;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS reply_to_message_id BIGINT;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS forward_from TEXT;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS file_id TEXT;
+ALTER TABLE message_revisions ADD COLUMN IF NOT EXISTS caption TEXT;
@@ -0,0 +1,2 @@
+-- Speech-to-text of voice, audio and video note messages, encrypted like content.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS transcript TEXT;
@@ -0,0 +1 @@
+ALTER TABLE group_settings DROP COLUMN IF EXISTS describe_images;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS group_settings;
+DROP TABLE IF EXISTS digest_schedules;
+DROP TABLE IF EXISTS message_revisions;
+DROP TABLE IF EXISTS messages;
@@ -0,0 +1,45 @@
+-- SQLite deployments start from the schema PostgreSQL reached through migrations 0001-0005.
+CREATE TABLE IF NOT EXISTS messages (
+    message_id INTEGER NOT NULL,
+    timestamp TIMESTAMP NOT NULL,
+    name TEXT,
+    last_name TEXT,
+    username TEXT,
+    group_id INTEGER NOT NULL,
+    user_id INTEGER,
+    content TEXT,
+    edited_at TIMESTAMP,
+    PRIMARY KEY (group_id, message_id)
+);
+
+CREATE INDEX IF NOT EXISTS messages_group_id_timestamp_idx ON messages (group_id, timestamp);
+
+CREATE TABLE IF NOT EXISTS message_revisions (
+    group_id INTEGER NOT NULL,
+    message_id INTEGER NOT NULL,
+    revision INTEGER NOT NULL,
+    edited_at TIMESTAMP NOT NULL,
+    content TEXT,
+    PRIMARY KEY (group_id, message_id, revision),
+    FOREIGN KEY (group_id, message_id) REFERENCES messages (group_id, message_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS digest_schedules (
+    group_id INTEGER PRIMARY KEY,
+    frequency TEXT NOT NULL,
+    weekday INTEGER NOT NULL DEFAULT 0,
+    hour INTEGER NOT NULL,
+    minute INTEGER NOT NULL,
+    timezone TEXT NOT NULL,
+    last_run TIMESTAMP,
+    next_run TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS group_settings (
+    group_id INTEGER PRIMARY KEY,
+    lang TEXT,
+    provider TEXT,
+    window_minutes INTEGER,
+    style TEXT,
+    updated_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,2 @@
+-- NULL uses RETENTION_DAYS, 0 keeps messages forever, N keeps them N days.
+ALTER TABLE group_settings ADD COLUMN retention_days INTEGER;
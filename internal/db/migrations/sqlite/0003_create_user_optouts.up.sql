@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS user_optouts (
+    user_id INTEGER PRIMARY KEY,
+    opted_out_at TIMESTAMP NOT NULL
+);
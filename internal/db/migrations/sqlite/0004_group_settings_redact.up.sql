@@ -0,0 +1,2 @@
+-- NULL uses REDACT_MODE; otherwise one of off, pii or all.
+ALTER TABLE group_settings ADD COLUMN redact TEXT;
@@ -0,0 +1,6 @@
+-- Encrypted rows hold base64 ciphertext in content, the ID of the key that wrapped
+-- their data key in key_id, and the wrapped data key. NULL key_id means plaintext.
+ALTER TABLE messages ADD COLUMN key_id TEXT;
+ALTER TABLE messages ADD COLUMN wrapped_key TEXT;
+ALTER TABLE message_revisions ADD COLUMN key_id TEXT;
+ALTER TABLE message_revisions ADD COLUMN wrapped_key TEXT;
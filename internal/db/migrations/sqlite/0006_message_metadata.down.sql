@@ -0,0 +1,6 @@
+ALTER TABLE message_revisions DROP COLUMN caption;
+ALTER TABLE messages DROP COLUMN file_id;
+ALTER TABLE messages DROP COLUMN forward_from;
+ALTER TABLE messages DROP COLUMN reply_to_message_id;
+ALTER TABLE messages DROP COLUMN caption;
+ALTER TABLE messages DROP COLUMN message_type;
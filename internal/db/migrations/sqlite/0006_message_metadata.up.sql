@@ -0,0 +1,8 @@
+-- What kind of message was logged and how it relates to others. NULL message_type
+-- marks text messages logged before types were recorded.
+ALTER TABLE messages ADD COLUMN message_type TEXT;
+ALTER TABLE messages ADD COLUMN caption TEXT;
+ALTER TABLE messages ADD COLUMN reply_to_message_id INTEGER;
+ALTER TABLE messages ADD COLUMN forward_from TEXT;
+ALTER TABLE messages ADD COLUMN file_id TEXT;
+ALTER TABLE message_revisions ADD COLUMN caption TEXT;
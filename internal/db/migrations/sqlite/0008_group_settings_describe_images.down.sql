@@ -0,0 +1 @@
+ALTER TABLE group_settings DROP COLUMN describe_images;
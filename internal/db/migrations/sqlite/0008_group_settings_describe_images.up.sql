@@ -0,0 +1,2 @@
+-- NULL uses DESCRIBE_IMAGES.
+ALTER TABLE group_settings ADD COLUMN describe_images BOOLEAN;
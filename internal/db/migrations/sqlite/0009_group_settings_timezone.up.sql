@@ -0,0 +1,2 @@
+-- NULL uses TIMEZONE; otherwise an IANA timezone name.
+ALTER TABLE group_settings ADD COLUMN timezone TEXT;
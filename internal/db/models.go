@@ -12,4 +12,103 @@ type Message struct {
 	GroupID   int64     `json:"group_id"`
 	UserID    int64     `json:"user_id"`
 	Content   string    `json:"content"`
+	// Type is the kind of message, one of the Message* constants.
+	Type string `json:"type"`
+	// Caption is the caption of a media message.
+	Caption string `json:"caption"`
+	// ReplyToMessageID is the message this one answers, if any.
+	ReplyToMessageID int64 `json:"reply_to_message_id"`
+	// ForwardFrom names the original sender of a forwarded message.
+	ForwardFrom string `json:"forward_from"`
+	// FileID is the Telegram file ID of the attached media; for photos, the largest size.
+	FileID string `json:"file_id"`
+	// Transcript is the speech-to-text of a voice, audio or video note message.
+	Transcript string `json:"transcript"`
+}
+
+// Message types. Content holds the text of text messages, the emoji of stickers,
+// and a plain rendering of polls, locations and contacts.
+const (
+	MessageText      = "text"
+	MessagePhoto     = "photo"
+	MessageVideo     = "video"
+	MessageAnimation = "animation"
+	MessageDocument  = "document"
+	MessageAudio     = "audio"
+	MessageVoice     = "voice"
+	MessageVideoNote = "video_note"
+	MessageSticker   = "sticker"
+	MessagePoll      = "poll"
+	MessageLocation  = "location"
+	MessageContact   = "contact"
+)
+
+// Query selects which messages of a group are retrieved for summarization.
+// Zero-valued fields are ignored.
+type Query struct {
+	// AnchorMessageID starts the range at the timestamp of this message.
+	AnchorMessageID int64
+	// Window limits the range to this duration after the anchor.
+	Window time.Duration
+	// EndMessageID ends the range at the timestamp of this message; it takes
+	// precedence over Window. The two anchors may be given in either order.
+	EndMessageID int64
+	// Since and Until bound the range when no anchor is set. Until is excluded.
+	Since time.Time
+	Until time.Time
+	// Limit keeps only the most recent Limit messages of the range; zero keeps up
+	// to MaxMessages.
+	Limit int
+}
+
+// Digest frequencies.
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// DigestSchedule describes when a group receives its automatic digest.
+type DigestSchedule struct {
+	GroupID   int64        `json:"group_id"`
+	Frequency string       `json:"frequency"`
+	Weekday   time.Weekday `json:"weekday"` // only used by weekly digests
+	Hour      int          `json:"hour"`
+	Minute    int          `json:"minute"`
+	Timezone  string       `json:"timezone"`
+	LastRun   time.Time    `json:"last_run"` // zero until the first digest
+	NextRun   time.Time    `json:"next_run"`
+}
+
+// RetainForever is the GroupSettings.RetentionDays value that disables purging for a group.
+const RetainForever = -1
+
+// GroupSettings holds the per-group overrides of the environment defaults.
+// Empty or zero fields mean "use the default".
+type GroupSettings struct {
+	GroupID  int64         `json:"group_id"`
+	Lang     string        `json:"lang"`
+	Provider string        `json:"provider"`
+	Window   time.Duration `json:"window"`
+	Style    string        `json:"style"`
+	// RetentionDays is how long messages are kept; RetainForever keeps them indefinitely.
+	RetentionDays int `json:"retention_days"`
+	// Redact is the redaction mode applied before prompting (see redact.Modes).
+	Redact string `json:"redact"`
+	// DescribeImages turns photo descriptions on or off; nil uses the default.
+	DescribeImages *bool `json:"describe_images"`
+	// Timezone is the IANA name of the timezone of the group.
+	Timezone string `json:"timezone"`
+}
+
+// Retention returns the number of days messages are kept in the group, given the
+// default from the environment. Zero means forever.
+func (s GroupSettings) Retention(defaultDays int) int {
+	switch {
+	case s.RetentionDays == RetainForever:
+		return 0
+	case s.RetentionDays > 0:
+		return s.RetentionDays
+	default:
+		return defaultDays
+	}
 }
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OptOut records that a user does not want their messages stored or summarized,
+// and deletes everything already stored from them. It returns how many messages were deleted.
+func (s *sqlStore) OptOut(ctx context.Context, userID int64) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, s.rebind(`INSERT INTO user_optouts (user_id, opted_out_at) VALUES ($1, $2)
+              ON CONFLICT (user_id) DO NOTHING`), userID, s.bindTime(time.Now()))
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.ExecContext(ctx, s.rebind(`DELETE FROM messages WHERE user_id = $1`), userID)
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return deleted, tx.Commit()
+}
+
+// OptIn lets a user's messages be stored again from now on.
+func (s *sqlStore) OptIn(ctx context.Context, userID int64) error {
+	_, err := s.exec(ctx, `DELETE FROM user_optouts WHERE user_id = $1`, userID)
+	return err
+}
+
+// IsOptedOut reports whether a user opted out.
+func (s *sqlStore) IsOptedOut(ctx context.Context, userID int64) (bool, error) {
+	var one int
+	err := s.queryRow(ctx, `SELECT 1 FROM user_optouts WHERE user_id = $1`, userID).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// GetOptedOutUsers returns which of the given users opted out.
+func (s *sqlStore) GetOptedOutUsers(ctx context.Context, userIDs []int64) (map[int64]bool, error) {
+	optedOut := map[int64]bool{}
+	if len(userIDs) == 0 {
+		return optedOut, nil
+	}
+
+	placeholders := make([]string, len(userIDs))
+	args := make([]interface{}, len(userIDs))
+	for i, userID := range userIDs {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = userID
+	}
+
+	rows, err := s.query(ctx, `SELECT user_id FROM user_optouts WHERE user_id IN (`+strings.Join(placeholders, ", ")+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		optedOut[userID] = true
+	}
+	return optedOut, rows.Err()
+}
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"time"
+
+	_ "github.com/lib/pq" // PostgreSQL driver
+)
+
+// migrationLockID is the Postgres advisory lock key serializing concurrent migrators.
+const migrationLockID = 727311
+
+// unlockTimeout bounds the release of the migration lock.
+const unlockTimeout = 5 * time.Second
+
+var postgresDialect = &dialect{
+	name:     "postgres",
+	driver:   "postgres",
+	location: time.Local,
+	migrationsTable: `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+`,
+	lock: func(ctx context.Context, conn *sql.Conn) (func(), error) {
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+			return nil, err
+		}
+		return func() {
+			// Unlock even once ctx is cancelled, or the connection would go back to the
+			// pool still holding the lock. Failing that, discard the connection: the
+			// lock ends with its session.
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), unlockTimeout)
+			defer cancel()
+			if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrationLockID); err != nil {
+				conn.Raw(func(any) error { return driver.ErrBadConn })
+			}
+		}, nil
+	},
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// GetGroupSettings returns the stored settings of a group. Groups without a row get
+// empty settings, meaning every value falls back to the environment defaults.
+func (s *sqlStore) GetGroupSettings(ctx context.Context, groupID int64) (GroupSettings, error) {
+	settings := GroupSettings{GroupID: groupID}
+
+	query := `SELECT lang, provider, window_minutes, style, retention_days, redact, describe_images, timezone FROM group_settings WHERE group_id = $1`
+	var lang, provider, style, redact, timezone sql.NullString
+	var windowMinutes, retentionDays sql.NullInt64
+	var describeImages sql.NullBool
+	err := s.queryRow(ctx, query, groupID).Scan(&lang, &provider, &windowMinutes, &style, &retentionDays, &redact, &describeImages, &timezone)
+	if err == sql.ErrNoRows {
+		return settings, nil
+	}
+	if err != nil {
+		return settings, err
+	}
+
+	settings.Lang = lang.String
+	settings.Provider = provider.String
+	settings.Window = time.Duration(windowMinutes.Int64) * time.Minute
+	settings.Style = style.String
+	settings.Redact = redact.String
+	settings.Timezone = timezone.String
+	if describeImages.Valid {
+		settings.DescribeImages = &describeImages.Bool
+	}
+	switch {
+	case !retentionDays.Valid:
+	case retentionDays.Int64 == 0:
+		settings.RetentionDays = RetainForever
+	default:
+		settings.RetentionDays = int(retentionDays.Int64)
+	}
+	return settings, nil
+}
+
+// SaveGroupSettings creates or replaces the settings of a group. Empty values are stored as NULL.
+func (s *sqlStore) SaveGroupSettings(ctx context.Context, settings GroupSettings) error {
+	query := `INSERT INTO group_settings (group_id, lang, provider, window_minutes, style, retention_days, redact, describe_images, timezone, updated_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+              ON CONFLICT (group_id) DO UPDATE SET
+                  lang = EXCLUDED.lang,
+                  provider = EXCLUDED.provider,
+                  window_minutes = EXCLUDED.window_minutes,
+                  style = EXCLUDED.style,
+                  retention_days = EXCLUDED.retention_days,
+                  redact = EXCLUDED.redact,
+                  describe_images = EXCLUDED.describe_images,
+                  timezone = EXCLUDED.timezone,
+                  updated_at = EXCLUDED.updated_at`
+
+	// The column stores 0 for "forever" and NULL for "use the default".
+	retentionDays := sql.NullInt64{Int64: int64(settings.RetentionDays), Valid: settings.RetentionDays != 0}
+	if settings.RetentionDays == RetainForever {
+		retentionDays.Int64 = 0
+	}
+
+	var describeImages sql.NullBool
+	if settings.DescribeImages != nil {
+		describeImages = sql.NullBool{Bool: *settings.DescribeImages, Valid: true}
+	}
+
+	_, err := s.exec(ctx, query,
+		settings.GroupID,
+		nullString(settings.Lang),
+		nullString(settings.Provider),
+		sql.NullInt64{Int64: int64(settings.Window / time.Minute), Valid: settings.Window > 0},
+		nullString(settings.Style),
+		retentionDays,
+		nullString(settings.Redact),
+		describeImages,
+		nullString(settings.Timezone),
+		s.bindTime(time.Now()),
+	)
+	return err
+}
+
+// DeleteGroupSettings resets a group to the environment defaults.
+func (s *sqlStore) DeleteGroupSettings(ctx context.Context, groupID int64) error {
+	_, err := s.exec(ctx, `DELETE FROM group_settings WHERE group_id = $1`, groupID)
+	return err
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // SQLite driver (pure Go, no cgo)
+)
+
+var sqliteDialect = &dialect{
+	name:           "sqlite",
+	driver:         "sqlite",
+	numberedParams: true,
+	// Timestamps are stored as text, so a single offset keeps them ordered.
+	location: time.UTC,
+	migrationsTable: `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at TIMESTAMP NOT NULL
+);
+`,
+	configure: func(database *sql.DB) {
+		// SQLite allows a single writer; one connection avoids "database is locked" errors.
+		database.SetMaxOpenConns(1)
+	},
+}
+
+// sqliteDSN turns the path of a sqlite:// URL into a driver DSN with the pragmas the store relies on.
+func sqliteDSN(path string) string {
+	path, params, _ := strings.Cut(path, "?")
+	pragmas := "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_time_format=sqlite"
+	if params != "" {
+		pragmas += "&" + params
+	}
+	return "file:" + path + "?" + pragmas
+}
@@ -0,0 +1,142 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MessageStore persists everything the bot keeps: logged messages, digest schedules
+// and group settings. Implementations differ only in the SQL database behind them.
+// Every call is bounded by its context.
+type MessageStore interface {
+	LogMessage(ctx context.Context, message Message) error
+	LogEdit(ctx context.Context, message Message, editedAt time.Time) error
+	SetTranscript(ctx context.Context, groupID, messageID int64, transcript string) error
+	GetMessages(ctx context.Context, groupID int64, q Query) ([]Message, error)
+	GetGroupIDs(ctx context.Context) ([]int64, error)
+	DeleteMessage(ctx context.Context, groupID, messageID int64) error
+	DeleteMessages(ctx context.Context, groupID int64, since, until time.Time) (int64, error)
+	PurgeMessages(ctx context.Context, groupID int64, before time.Time, limit int) (int64, error)
+
+	GetDigestSchedule(ctx context.Context, groupID int64) (*DigestSchedule, error)
+	GetDueDigestSchedules(ctx context.Context, now time.Time) ([]DigestSchedule, error)
+	SaveDigestSchedule(ctx context.Context, schedule DigestSchedule) error
+	AdvanceDigestSchedule(ctx context.Context, groupID int64, nextRun time.Time) error
+	MarkDigestRun(ctx context.Context, groupID int64, lastRun time.Time) error
+	DeleteDigestSchedule(ctx context.Context, groupID int64) error
+
+	OptOut(ctx context.Context, userID int64) (int64, error)
+	OptIn(ctx context.Context, userID int64) error
+	IsOptedOut(ctx context.Context, userID int64) (bool, error)
+	GetOptedOutUsers(ctx context.Context, userIDs []int64) (map[int64]bool, error)
+
+	GetGroupSettings(ctx context.Context, groupID int64) (GroupSettings, error)
+	SaveGroupSettings(ctx context.Context, settings GroupSettings) error
+	DeleteGroupSettings(ctx context.Context, groupID int64) error
+
+	MigrateUp(ctx context.Context) (int, error)
+	MigrateDown(ctx context.Context, steps int) (int, error)
+	MigrationStatus(ctx context.Context) ([]MigrationState, error)
+	RotateKeys(ctx context.Context, batchSize int) (Rotation, error)
+	DecryptAll(ctx context.Context, batchSize int) (Rotation, error)
+
+	Close() error
+}
+
+// Open connects to the store described by url and checks that it is reachable.
+// postgres:// and postgresql:// URLs select PostgreSQL; sqlite:// URLs select an
+// embedded SQLite database, e.g. sqlite:///var/lib/tldr/bot.db or sqlite://bot.db.
+// Message content is encrypted with keys when it is not nil.
+func Open(url string, keys *Keyring) (MessageStore, error) {
+	scheme, _, _ := strings.Cut(url, "://")
+
+	var d *dialect
+	var dsn string
+	switch strings.ToLower(scheme) {
+	case "postgres", "postgresql":
+		d, dsn = postgresDialect, url
+	case "sqlite", "sqlite3":
+		d, dsn = sqliteDialect, sqliteDSN(strings.TrimPrefix(url, scheme+"://"))
+	default:
+		return nil, fmt.Errorf("unsupported DATABASE_URL scheme %q (expected postgres:// or sqlite://)", scheme)
+	}
+
+	database, err := sql.Open(d.driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+	if d.configure != nil {
+		d.configure(database)
+	}
+	if err := database.Ping(); err != nil {
+		database.Close()
+		return nil, fmt.Errorf("connecting to the database: %w", err)
+	}
+	return &sqlStore{db: database, dialect: d, keys: keys}, nil
+}
+
+// dialect captures what differs between the supported SQL databases.
+type dialect struct {
+	// name is also the directory holding the dialect's migrations.
+	name   string
+	driver string
+	// numberedParams rewrites $N placeholders to ?N.
+	numberedParams bool
+	// location is the timezone message timestamps are stored in. PostgreSQL
+	// TIMESTAMP columns keep the wall clock only, so it must match on read and write.
+	location *time.Location
+	// migrationsTable is the DDL of the schema_migrations table.
+	migrationsTable string
+	// lock serializes migrators across processes; nil when not needed.
+	lock func(ctx context.Context, conn *sql.Conn) (unlock func(), err error)
+	// configure tunes the connection pool after opening.
+	configure func(database *sql.DB)
+}
+
+// sqlStore implements MessageStore on top of database/sql.
+type sqlStore struct {
+	db      *sql.DB
+	dialect *dialect
+	// keys encrypts message content; nil stores it in plaintext.
+	keys *Keyring
+}
+
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
+
+var placeholder = regexp.MustCompile(`\$(\d+)`)
+
+// rebind adapts a query written with PostgreSQL placeholders to the store's dialect.
+func (s *sqlStore) rebind(query string) string {
+	if !s.dialect.numberedParams {
+		return query
+	}
+	return placeholder.ReplaceAllString(query, "?$1")
+}
+
+func (s *sqlStore) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return s.db.ExecContext(ctx, s.rebind(query), args...)
+}
+
+func (s *sqlStore) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return s.db.QueryContext(ctx, s.rebind(query), args...)
+}
+
+func (s *sqlStore) queryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return s.db.QueryRowContext(ctx, s.rebind(query), args...)
+}
+
+// bindTime converts a time before it is written to or compared with a message timestamp.
+func (s *sqlStore) bindTime(t time.Time) time.Time {
+	return t.In(s.dialect.location)
+}
+
+// scanTime restores the instant of a message timestamp read back from the database.
+func (s *sqlStore) scanTime(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), s.dialect.location)
+}
@@ -0,0 +1,619 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// openTestStore returns a migrated SQLite store in a temporary directory.
+func openTestStore(t *testing.T) *sqlStore {
+	t.Helper()
+	store, err := Open("sqlite://"+filepath.Join(t.TempDir(), "bot.db"), nil)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if _, err := store.MigrateUp(context.Background()); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	return store.(*sqlStore)
+}
+
+const testGroup = -100
+
+var base = time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC)
+
+// logMinutes logs one message per offset, with the offset as ID and content, that
+// many minutes after base.
+func logMinutes(t *testing.T, store *sqlStore, offsets ...int) {
+	t.Helper()
+	for _, m := range offsets {
+		msg := Message{
+			MessageID: int64(m),
+			Timestamp: base.Add(time.Duration(m) * time.Minute),
+			Name:      "Alice",
+			GroupID:   testGroup,
+			UserID:    1,
+			Content:   fmt.Sprintf("message %d", m),
+			Type:      MessageText,
+		}
+		if err := store.LogMessage(context.Background(), msg); err != nil {
+			t.Fatalf("LogMessage: %v", err)
+		}
+	}
+}
+
+func messageIDs(messages []Message) []int64 {
+	ids := []int64{}
+	for _, msg := range messages {
+		ids = append(ids, msg.MessageID)
+	}
+	return ids
+}
+
+func TestGetMessages(t *testing.T) {
+	store := openTestStore(t)
+	logMinutes(t, store, 0, 10, 20, 30, 40)
+	// Another group's messages never show up.
+	if err := store.LogMessage(context.Background(), Message{MessageID: 15, Timestamp: base.Add(15 * time.Minute), GroupID: 1, Content: "x"}); err != nil {
+		t.Fatalf("LogMessage: %v", err)
+	}
+
+	for name, tc := range map[string]struct {
+		query Query
+		want  []int64
+	}{
+		"everything":          {Query{}, []int64{0, 10, 20, 30, 40}},
+		"since":               {Query{Since: base.Add(20 * time.Minute)}, []int64{20, 30, 40}},
+		"until is excluded":   {Query{Since: base.Add(10 * time.Minute), Until: base.Add(30 * time.Minute)}, []int64{10, 20}},
+		"limit keeps newest":  {Query{Limit: 2}, []int64{30, 40}},
+		"limit within window": {Query{Until: base.Add(30 * time.Minute), Limit: 2}, []int64{10, 20}},
+		"anchor and window":   {Query{AnchorMessageID: 10, Window: 20 * time.Minute}, []int64{10, 20}},
+		"anchor to end":       {Query{AnchorMessageID: 10, EndMessageID: 30}, []int64{10, 20, 30}},
+		"anchors swapped":     {Query{AnchorMessageID: 30, EndMessageID: 10}, []int64{10, 20, 30}},
+		"unknown anchor":      {Query{AnchorMessageID: 99}, []int64{}},
+		"empty window":        {Query{Since: base.Add(time.Hour)}, []int64{}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			messages, err := store.GetMessages(context.Background(), testGroup, tc.query)
+			if err != nil {
+				t.Fatalf("GetMessages: %v", err)
+			}
+			if got := messageIDs(messages); !slices.Equal(got, tc.want) {
+				t.Errorf("messages = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	messages, err := store.GetMessages(context.Background(), testGroup, Query{Limit: 1})
+	if err != nil {
+		t.Fatalf("GetMessages: %v", err)
+	}
+	if got := messages[0]; got.Content != "message 40" || got.Name != "Alice" || !got.Timestamp.Equal(base.Add(40*time.Minute)) {
+		t.Errorf("message = %+v, want the one logged 40 minutes after base", got)
+	}
+}
+
+func TestLogMessageIgnoresDuplicates(t *testing.T) {
+	store := openTestStore(t)
+	logMinutes(t, store, 0, 0)
+
+	messages, err := store.GetMessages(context.Background(), testGroup, Query{})
+	if err != nil {
+		t.Fatalf("GetMessages: %v", err)
+	}
+	if len(messages) != 1 {
+		t.Errorf("got %d messages, want 1", len(messages))
+	}
+}
+
+func TestLogEdit(t *testing.T) {
+	ctx := context.Background()
+	store := openTestStore(t)
+	logMinutes(t, store, 0)
+
+	for i, content := range []string{"first edit", "second edit"} {
+		edit := Message{MessageID: 0, Timestamp: base, GroupID: testGroup, UserID: 1, Content: content, Type: MessageText}
+		if err := store.LogEdit(ctx, edit, base.Add(time.Duration(i+1)*time.Minute)); err != nil {
+			t.Fatalf("LogEdit: %v", err)
+		}
+	}
+
+	messages, err := store.GetMessages(ctx, testGroup, Query{})
+	if err != nil {
+		t.Fatalf("GetMessages: %v", err)
+	}
+	if len(messages) != 1 || messages[0].Content != "second edit" {
+		t.Fatalf("messages = %+v, want the second edit only", messages)
+	}
+
+	rows, err := store.query(ctx, `SELECT revision, content FROM message_revisions WHERE group_id = $1 AND message_id = $2 ORDER BY revision`, testGroup, 0)
+	if err != nil {
+		t.Fatalf("reading revisions: %v", err)
+	}
+	defer rows.Close()
+	var revisions []string
+	for rows.Next() {
+		var revision int
+		var content string
+		if err := rows.Scan(&revision, &content); err != nil {
+			t.Fatalf("reading revisions: %v", err)
+		}
+		if revision != len(revisions) {
+			t.Errorf("revision %d found at position %d", revision, len(revisions))
+		}
+		revisions = append(revisions, content)
+	}
+	if want := []string{"message 0", "first edit", "second edit"}; !slices.Equal(revisions, want) {
+		t.Errorf("revisions = %q, want %q", revisions, want)
+	}
+
+	// Edits of messages that were never logged are stored as new messages.
+	unseen := Message{MessageID: 7, Timestamp: base.Add(7 * time.Minute), GroupID: testGroup, UserID: 1, Content: "late", Type: MessageText}
+	if err := store.LogEdit(ctx, unseen, base.Add(8*time.Minute)); err != nil {
+		t.Fatalf("LogEdit: %v", err)
+	}
+	messages, err = store.GetMessages(ctx, testGroup, Query{})
+	if err != nil {
+		t.Fatalf("GetMessages: %v", err)
+	}
+	if got := messageIDs(messages); !slices.Equal(got, []int64{0, 7}) {
+		t.Errorf("messages = %v, want [0 7]", got)
+	}
+}
+
+func TestDeleteMessages(t *testing.T) {
+	ctx := context.Background()
+	store := openTestStore(t)
+	logMinutes(t, store, 0, 10, 20, 30, 40)
+
+	deleted, err := store.DeleteMessages(ctx, testGroup, base.Add(10*time.Minute), base.Add(30*time.Minute))
+	if err != nil {
+		t.Fatalf("DeleteMessages: %v", err)
+	}
+	if deleted != 3 {
+		t.Errorf("deleted %d messages, want 3", deleted)
+	}
+
+	deleted, err = store.DeleteMessages(ctx, testGroup, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("DeleteMessages: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("deleted %d messages, want the remaining 2", deleted)
+	}
+}
+
+func TestOptOut(t *testing.T) {
+	ctx := context.Background()
+	store := openTestStore(t)
+	for _, msg := range []Message{
+		{MessageID: 1, Timestamp: base, GroupID: testGroup, UserID: 1, Content: "mine", Type: MessageText},
+		{MessageID: 2, Timestamp: base, GroupID: testGroup, UserID: 1, Type: MessageVoice},
+		{MessageID: 3, Timestamp: base, GroupID: testGroup - 1, UserID: 1, Content: "other group", Type: MessageText},
+		{MessageID: 4, Timestamp: base, GroupID: testGroup, UserID: 2, Content: "someone else", Type: MessageText},
+	} {
+		if err := store.LogMessage(ctx, msg); err != nil {
+			t.Fatalf("LogMessage: %v", err)
+		}
+	}
+	edited := Message{MessageID: 1, Timestamp: base, GroupID: testGroup, UserID: 1, Content: "mine, edited", Type: MessageText}
+	if err := store.LogEdit(ctx, edited, base.Add(time.Minute)); err != nil {
+		t.Fatalf("LogEdit: %v", err)
+	}
+	if err := store.SetTranscript(ctx, testGroup, 2, "spoken words"); err != nil {
+		t.Fatalf("SetTranscript: %v", err)
+	}
+
+	deleted, err := store.OptOut(ctx, 1)
+	if err != nil {
+		t.Fatalf("OptOut: %v", err)
+	}
+	if deleted != 3 {
+		t.Errorf("OptOut deleted %d messages, want 3 across both groups", deleted)
+	}
+
+	var left, revisions int
+	if err := store.queryRow(ctx, `SELECT COUNT(*) FROM messages WHERE user_id = $1`, 1).Scan(&left); err != nil {
+		t.Fatalf("counting messages: %v", err)
+	}
+	if err := store.queryRow(ctx, `SELECT COUNT(*) FROM message_revisions`).Scan(&revisions); err != nil {
+		t.Fatalf("counting revisions: %v", err)
+	}
+	if left != 0 || revisions != 0 {
+		t.Errorf("%d message(s) and %d revision(s) left, want none", left, revisions)
+	}
+	messages, err := store.GetMessages(ctx, testGroup, Query{})
+	if err != nil {
+		t.Fatalf("GetMessages: %v", err)
+	}
+	if ids := messageIDs(messages); !slices.Equal(ids, []int64{4}) {
+		t.Errorf("messages left = %v, want only the other user's", ids)
+	}
+
+	// Opting out twice keeps the record and finds nothing more to delete.
+	if deleted, err := store.OptOut(ctx, 1); err != nil || deleted != 0 {
+		t.Errorf("second OptOut = %d, %v; want 0, nil", deleted, err)
+	}
+	if optedOut, err := store.IsOptedOut(ctx, 1); err != nil || !optedOut {
+		t.Errorf("IsOptedOut(1) = %v, %v; want true", optedOut, err)
+	}
+	if optedOut, err := store.IsOptedOut(ctx, 2); err != nil || optedOut {
+		t.Errorf("IsOptedOut(2) = %v, %v; want false", optedOut, err)
+	}
+
+	if err := store.OptIn(ctx, 1); err != nil {
+		t.Fatalf("OptIn: %v", err)
+	}
+	if optedOut, err := store.IsOptedOut(ctx, 1); err != nil || optedOut {
+		t.Errorf("IsOptedOut after OptIn = %v, %v; want false", optedOut, err)
+	}
+}
+
+func TestGetOptedOutUsers(t *testing.T) {
+	ctx := context.Background()
+	store := openTestStore(t)
+	for _, userID := range []int64{1, 3} {
+		if _, err := store.OptOut(ctx, userID); err != nil {
+			t.Fatalf("OptOut: %v", err)
+		}
+	}
+
+	optedOut, err := store.GetOptedOutUsers(ctx, []int64{1, 2, 3, 4})
+	if err != nil {
+		t.Fatalf("GetOptedOutUsers: %v", err)
+	}
+	if len(optedOut) != 2 || !optedOut[1] || !optedOut[3] {
+		t.Errorf("GetOptedOutUsers = %v, want users 1 and 3", optedOut)
+	}
+	if optedOut, err := store.GetOptedOutUsers(ctx, nil); err != nil || len(optedOut) != 0 {
+		t.Errorf("GetOptedOutUsers(nil) = %v, %v; want none", optedOut, err)
+	}
+}
+
+func TestGroupSettings(t *testing.T) {
+	ctx := context.Background()
+	store := openTestStore(t)
+
+	settings, err := store.GetGroupSettings(ctx, testGroup)
+	if err != nil {
+		t.Fatalf("GetGroupSettings: %v", err)
+	}
+	if !reflect.DeepEqual(settings, GroupSettings{GroupID: testGroup}) {
+		t.Errorf("settings of a new group = %+v, want none", settings)
+	}
+
+	off := false
+	saved := GroupSettings{
+		GroupID: testGroup, Lang: "pt", Provider: "ollama", Window: 90 * time.Minute, Style: "bullets",
+		RetentionDays: 30, Redact: "all", DescribeImages: &off, Timezone: "America/Sao_Paulo",
+	}
+	if err := store.SaveGroupSettings(ctx, saved); err != nil {
+		t.Fatalf("SaveGroupSettings: %v", err)
+	}
+	if settings, err = store.GetGroupSettings(ctx, testGroup); err != nil {
+		t.Fatalf("GetGroupSettings: %v", err)
+	}
+	if !reflect.DeepEqual(settings, saved) {
+		t.Errorf("settings = %+v, want %+v", settings, saved)
+	}
+
+	// The column holds NULL for the default and 0 for forever.
+	for _, tc := range []struct {
+		days   int
+		stored sql.NullInt64
+	}{
+		{0, sql.NullInt64{}},
+		{RetainForever, sql.NullInt64{Int64: 0, Valid: true}},
+		{7, sql.NullInt64{Int64: 7, Valid: true}},
+	} {
+		if err := store.SaveGroupSettings(ctx, GroupSettings{GroupID: testGroup, RetentionDays: tc.days}); err != nil {
+			t.Fatalf("SaveGroupSettings: %v", err)
+		}
+		var stored sql.NullInt64
+		if err := store.queryRow(ctx, `SELECT retention_days FROM group_settings WHERE group_id = $1`, testGroup).Scan(&stored); err != nil {
+			t.Fatalf("reading retention_days: %v", err)
+		}
+		if stored != tc.stored {
+			t.Errorf("retention %d stored as %+v, want %+v", tc.days, stored, tc.stored)
+		}
+		settings, err := store.GetGroupSettings(ctx, testGroup)
+		if err != nil {
+			t.Fatalf("GetGroupSettings: %v", err)
+		}
+		if !reflect.DeepEqual(settings, GroupSettings{GroupID: testGroup, RetentionDays: tc.days}) {
+			t.Errorf("retention %d read back as %+v", tc.days, settings)
+		}
+	}
+
+	if err := store.DeleteGroupSettings(ctx, testGroup); err != nil {
+		t.Fatalf("DeleteGroupSettings: %v", err)
+	}
+	if settings, err = store.GetGroupSettings(ctx, testGroup); err != nil || settings.RetentionDays != 0 {
+		t.Errorf("settings after DeleteGroupSettings = %+v, %v; want none", settings, err)
+	}
+}
+
+func TestRebind(t *testing.T) {
+	query := `SELECT * FROM messages WHERE group_id = $1 AND message_id IN ($2, $10) AND content <> '$'`
+
+	sqlite := &sqlStore{dialect: sqliteDialect}
+	want := `SELECT * FROM messages WHERE group_id = ?1 AND message_id IN (?2, ?10) AND content <> '$'`
+	if got := sqlite.rebind(query); got != want {
+		t.Errorf("sqlite rebind = %q, want %q", got, want)
+	}
+
+	postgres := &sqlStore{dialect: postgresDialect}
+	if got := postgres.rebind(query); got != query {
+		t.Errorf("postgres rebind = %q, want it unchanged", got)
+	}
+}
+
+func TestLogEditEncryptsPlaintextTranscript(t *testing.T) {
+	ctx := context.Background()
+	plain := openTestStore(t)
+	voice := Message{MessageID: 1, Timestamp: base, GroupID: testGroup, UserID: 1, Type: MessageVoice}
+	if err := plain.LogMessage(ctx, voice); err != nil {
+		t.Fatalf("LogMessage: %v", err)
+	}
+	if err := plain.SetTranscript(ctx, testGroup, 1, "spoken words"); err != nil {
+		t.Fatalf("SetTranscript: %v", err)
+	}
+
+	// Encryption is turned on after the message was logged.
+	keys, err := ParseKeyring("k1:" + base64.StdEncoding.EncodeToString(make([]byte, dataKeySize)))
+	if err != nil {
+		t.Fatalf("ParseKeyring: %v", err)
+	}
+	store := &sqlStore{db: plain.db, dialect: plain.dialect, keys: keys}
+	voice.Caption = "edited caption"
+	if err := store.LogEdit(ctx, voice, base.Add(time.Minute)); err != nil {
+		t.Fatalf("LogEdit: %v", err)
+	}
+
+	messages, err := store.GetMessages(ctx, testGroup, Query{})
+	if err != nil {
+		t.Fatalf("GetMessages: %v", err)
+	}
+	if len(messages) != 1 || messages[0].Caption != "edited caption" || messages[0].Transcript != "spoken words" {
+		t.Fatalf("messages = %+v, want the edited caption and the transcript", messages)
+	}
+	var stored string
+	if err := store.queryRow(ctx, `SELECT transcript FROM messages WHERE group_id = $1 AND message_id = $2`, testGroup, 1).Scan(&stored); err != nil {
+		t.Fatalf("reading transcript: %v", err)
+	}
+	if stored == "spoken words" {
+		t.Error("transcript is still stored in plaintext")
+	}
+}
+
+func TestSetTranscriptEncryptsPlaintextRows(t *testing.T) {
+	ctx := context.Background()
+	plain := openTestStore(t)
+	voice := Message{MessageID: 1, Timestamp: base, GroupID: testGroup, UserID: 1, Type: MessageVoice, Caption: "listen"}
+	if err := plain.LogMessage(ctx, voice); err != nil {
+		t.Fatalf("LogMessage: %v", err)
+	}
+
+	// Encryption is turned on while the message is being transcribed.
+	store := withKeys(t, plain, "k1")
+	if err := store.SetTranscript(ctx, testGroup, 1, "spoken words"); err != nil {
+		t.Fatalf("SetTranscript: %v", err)
+	}
+
+	if got := keyIDs(t, store, "messages"); !slices.Equal(got, []string{"k1"}) {
+		t.Errorf("key IDs = %q, want the row sealed with k1", got)
+	}
+	var caption, transcript string
+	if err := store.queryRow(ctx, `SELECT caption, transcript FROM messages WHERE group_id = $1 AND message_id = $2`, testGroup, 1).Scan(&caption, &transcript); err != nil {
+		t.Fatalf("reading row: %v", err)
+	}
+	if caption == "listen" || transcript == "spoken words" {
+		t.Errorf("caption %q and transcript %q, want both encrypted", caption, transcript)
+	}
+	messages, err := store.GetMessages(ctx, testGroup, Query{})
+	if err != nil {
+		t.Fatalf("GetMessages: %v", err)
+	}
+	if len(messages) != 1 || messages[0].Caption != "listen" || messages[0].Transcript != "spoken words" {
+		t.Fatalf("messages = %+v, want the caption and the transcript", messages)
+	}
+}
+
+// withKeys returns a store sharing the database of store, encrypting with the keys
+// named by ids, the first one active. A key ID always maps to the same key.
+func withKeys(t *testing.T, store *sqlStore, ids ...string) *sqlStore {
+	t.Helper()
+	var specs []string
+	for _, id := range ids {
+		key := make([]byte, dataKeySize)
+		copy(key, id)
+		specs = append(specs, id+":"+base64.StdEncoding.EncodeToString(key))
+	}
+	keys, err := ParseKeyring(strings.Join(specs, ","))
+	if err != nil {
+		t.Fatalf("ParseKeyring: %v", err)
+	}
+	return &sqlStore{db: store.db, dialect: store.dialect, keys: keys}
+}
+
+// keyIDs returns the distinct key IDs stored in table, "" standing for plaintext.
+func keyIDs(t *testing.T, store *sqlStore, table string) []string {
+	t.Helper()
+	rows, err := store.query(context.Background(), `SELECT DISTINCT COALESCE(key_id, '') FROM `+table+` ORDER BY 1`)
+	if err != nil {
+		t.Fatalf("reading key IDs: %v", err)
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("reading key IDs: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// revisionTexts returns the content of the revisions of a message, in order.
+func revisionTexts(t *testing.T, store *sqlStore, messageID int64) []string {
+	t.Helper()
+	rows, err := store.query(context.Background(), `SELECT content FROM message_revisions
+              WHERE group_id = $1 AND message_id = $2 ORDER BY revision`, testGroup, messageID)
+	if err != nil {
+		t.Fatalf("reading revisions: %v", err)
+	}
+	defer rows.Close()
+	var texts []string
+	for rows.Next() {
+		var text string
+		if err := rows.Scan(&text); err != nil {
+			t.Fatalf("reading revisions: %v", err)
+		}
+		texts = append(texts, text)
+	}
+	return texts
+}
+
+func TestRotateKeysAndDecryptAll(t *testing.T) {
+	ctx := context.Background()
+	plain := openTestStore(t)
+	logMinutes(t, plain, 1) // logged before encryption was enabled
+
+	old := withKeys(t, plain, "k1")
+	logMinutes(t, old, 2)
+	edited := Message{MessageID: 2, Timestamp: base, GroupID: testGroup, UserID: 1, Content: "message 2, edited", Type: MessageText}
+	if err := old.LogEdit(ctx, edited, base.Add(time.Hour)); err != nil {
+		t.Fatalf("LogEdit: %v", err)
+	}
+
+	rotated := withKeys(t, plain, "k2", "k1")
+	rotation, err := rotated.RotateKeys(ctx, 1)
+	if err != nil {
+		t.Fatalf("RotateKeys: %v", err)
+	}
+	// Both messages and both revisions of message 2.
+	if rotation.Updated != 4 || rotation.Skipped != 0 {
+		t.Errorf("RotateKeys = %+v, want 4 rows updated", rotation)
+	}
+	for _, table := range []string{"messages", "message_revisions"} {
+		if ids := keyIDs(t, plain, table); !slices.Equal(ids, []string{"k2"}) {
+			t.Errorf("key IDs of %s = %q, want only k2", table, ids)
+		}
+	}
+
+	// k1 is no longer needed once every row moved to k2.
+	current := withKeys(t, plain, "k2")
+	messages, err := current.GetMessages(ctx, testGroup, Query{})
+	if err != nil {
+		t.Fatalf("GetMessages: %v", err)
+	}
+	if len(messages) != 2 || messages[0].Content != "message 1" || messages[1].Content != "message 2, edited" {
+		t.Fatalf("messages = %+v, want message 1 and the edit of message 2", messages)
+	}
+
+	rotation, err = current.DecryptAll(ctx, 1)
+	if err != nil {
+		t.Fatalf("DecryptAll: %v", err)
+	}
+	if rotation.Updated != 4 {
+		t.Errorf("DecryptAll = %+v, want 4 rows updated", rotation)
+	}
+	for _, table := range []string{"messages", "message_revisions"} {
+		if ids := keyIDs(t, plain, table); !slices.Equal(ids, []string{""}) {
+			t.Errorf("key IDs of %s = %q, want plaintext only", table, ids)
+		}
+	}
+	if texts := revisionTexts(t, plain, 2); !slices.Equal(texts, []string{"message 2", "message 2, edited"}) {
+		t.Errorf("revisions = %q, want the original and the edit", texts)
+	}
+	messages, err = plain.GetMessages(ctx, testGroup, Query{})
+	if err != nil {
+		t.Fatalf("GetMessages without keys: %v", err)
+	}
+	if len(messages) != 2 || messages[1].Content != "message 2, edited" {
+		t.Fatalf("messages = %+v, want them in plaintext", messages)
+	}
+}
+
+func TestUnknownKeyIsRefused(t *testing.T) {
+	ctx := context.Background()
+	plain := openTestStore(t)
+	logMinutes(t, withKeys(t, plain, "k1"), 1)
+
+	other := withKeys(t, plain, "k2")
+	if _, err := other.GetMessages(ctx, testGroup, Query{}); err == nil || !strings.Contains(err.Error(), `unknown encryption key "k1"`) {
+		t.Errorf("GetMessages error = %v, want unknown key k1", err)
+	}
+	if _, err := other.RotateKeys(ctx, 10); err == nil {
+		t.Error("RotateKeys succeeded without the key of the stored rows")
+	}
+	if _, err := other.DecryptAll(ctx, 10); err == nil {
+		t.Error("DecryptAll succeeded without the key of the stored rows")
+	}
+	if ids := keyIDs(t, plain, "messages"); !slices.Equal(ids, []string{"k1"}) {
+		t.Errorf("key IDs = %q, want the row left under k1", ids)
+	}
+}
+
+func TestRevisionsCannotBeSwapped(t *testing.T) {
+	ctx := context.Background()
+	store := withKeys(t, openTestStore(t), "k1")
+	logMinutes(t, store, 1)
+	for i, text := range []string{"first edit", "second edit"} {
+		edited := Message{MessageID: 1, Timestamp: base, GroupID: testGroup, UserID: 1, Content: text, Type: MessageText}
+		if err := store.LogEdit(ctx, edited, base.Add(time.Duration(i+1)*time.Hour)); err != nil {
+			t.Fatalf("LogEdit: %v", err)
+		}
+	}
+
+	// Move the ciphertext of revision 2 into revision 1; both share a data key.
+	_, err := store.exec(ctx, `UPDATE message_revisions SET content = (SELECT content FROM message_revisions
+              WHERE group_id = $1 AND message_id = 1 AND revision = 2) WHERE group_id = $1 AND message_id = 1 AND revision = 1`, testGroup)
+	if err != nil {
+		t.Fatalf("swapping revisions: %v", err)
+	}
+	if _, err := store.DecryptAll(ctx, 10); err == nil || !strings.Contains(err.Error(), "decrypting content") {
+		t.Errorf("DecryptAll error = %v, want the swapped revision to fail to decrypt", err)
+	}
+}
+
+func TestRotateRowSkipsEditedRows(t *testing.T) {
+	ctx := context.Background()
+	plain := openTestStore(t)
+	old := withKeys(t, plain, "k1")
+	logMinutes(t, old, 1)
+
+	rotated := withKeys(t, plain, "k2", "k1")
+	table := encryptedTables[0]
+	stale, err := rotated.staleRows(ctx, table, 10, false)
+	if err != nil || len(stale) != 1 {
+		t.Fatalf("staleRows = %d row(s), %v; want 1", len(stale), err)
+	}
+
+	// The message is edited after rotate-keys read it.
+	edited := Message{MessageID: 1, Timestamp: base, GroupID: testGroup, UserID: 1, Content: "edited", Type: MessageText}
+	if err := old.LogEdit(ctx, edited, base.Add(time.Hour)); err != nil {
+		t.Fatalf("LogEdit: %v", err)
+	}
+	if updated, err := rotated.rotateRow(ctx, table, stale[0], false); err != nil || updated {
+		t.Fatalf("rotateRow = %v, %v; want the edited row skipped", updated, err)
+	}
+
+	messages, err := rotated.GetMessages(ctx, testGroup, Query{})
+	if err != nil {
+		t.Fatalf("GetMessages: %v", err)
+	}
+	if len(messages) != 1 || messages[0].Content != "edited" {
+		t.Fatalf("messages = %+v, want the edit kept", messages)
+	}
+}
@@ -0,0 +1,91 @@
+package janitor
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"tldr-telegram-bot/internal/db"
+)
+
+const (
+	// purgeInterval is how often expired messages are looked for.
+	purgeInterval = time.Hour
+	// batchSize bounds each DELETE so purges never hold long locks.
+	batchSize = 1000
+	// batchPause lets other queries through between batches.
+	batchPause = 100 * time.Millisecond
+)
+
+// Janitor deletes messages older than the retention period of their group.
+type Janitor struct {
+	store       db.MessageStore
+	defaultDays int
+}
+
+// New creates a janitor; defaultDays applies to groups without their own
+// retention setting, and zero keeps their messages forever.
+func New(store db.MessageStore, defaultDays int) *Janitor {
+	return &Janitor{store: store, defaultDays: defaultDays}
+}
+
+// Run purges expired messages every hour until ctx is cancelled.
+func (j *Janitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	j.purge(ctx, time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			j.purge(ctx, now)
+		}
+	}
+}
+
+func (j *Janitor) purge(ctx context.Context, now time.Time) {
+	groupIDs, err := j.store.GetGroupIDs(ctx)
+	if err != nil {
+		log.Printf("Error listing groups to purge: %v", err)
+		return
+	}
+
+	for _, groupID := range groupIDs {
+		if ctx.Err() != nil {
+			return
+		}
+		settings, err := j.store.GetGroupSettings(ctx, groupID)
+		if err != nil {
+			log.Printf("Error loading settings of group %d: %v", groupID, err)
+			continue
+		}
+		days := settings.Retention(j.defaultDays)
+		if days <= 0 {
+			continue
+		}
+
+		cutoff := now.AddDate(0, 0, -days)
+		var total int64
+		for {
+			deleted, err := j.store.PurgeMessages(ctx, groupID, cutoff, batchSize)
+			if err != nil {
+				log.Printf("Error purging messages of group %d: %v", groupID, err)
+				break
+			}
+			total += deleted
+			if deleted < batchSize {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(batchPause):
+			}
+		}
+		if total > 0 {
+			log.Printf("Purged %d message(s) older than %d day(s) from group %d", total, days, groupID)
+		}
+	}
+}
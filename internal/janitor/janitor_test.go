@@ -0,0 +1,100 @@
+package janitor
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"tldr-telegram-bot/internal/db"
+)
+
+var now = time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC)
+
+const (
+	defaultGroup = -1 // follows the default retention
+	shortGroup   = -2 // keeps messages 2 days
+	foreverGroup = -3 // keeps messages forever
+)
+
+// openStore returns a migrated SQLite store holding, in every test group, messages
+// logged 1, 3 and 10 days before now, with their age in days as ID.
+func openStore(t *testing.T) db.MessageStore {
+	t.Helper()
+	ctx := context.Background()
+	store, err := db.Open("sqlite://"+filepath.Join(t.TempDir(), "bot.db"), nil)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if _, err := store.MigrateUp(ctx); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+
+	for _, groupID := range []int64{defaultGroup, shortGroup, foreverGroup} {
+		for _, age := range []int{1, 3, 10} {
+			msg := db.Message{
+				MessageID: int64(age),
+				Timestamp: now.AddDate(0, 0, -age),
+				GroupID:   groupID,
+				UserID:    1,
+				Content:   "hello",
+				Type:      db.MessageText,
+			}
+			if err := store.LogMessage(ctx, msg); err != nil {
+				t.Fatalf("LogMessage: %v", err)
+			}
+		}
+	}
+	for groupID, days := range map[int64]int{shortGroup: 2, foreverGroup: db.RetainForever} {
+		if err := store.SaveGroupSettings(ctx, db.GroupSettings{GroupID: groupID, RetentionDays: days}); err != nil {
+			t.Fatalf("SaveGroupSettings: %v", err)
+		}
+	}
+	return store
+}
+
+// remaining returns the IDs, that is the ages, of the messages left in a group.
+func remaining(t *testing.T, store db.MessageStore, groupID int64) []int64 {
+	t.Helper()
+	messages, err := store.GetMessages(context.Background(), groupID, db.Query{})
+	if err != nil {
+		t.Fatalf("GetMessages: %v", err)
+	}
+	ids := []int64{}
+	for _, msg := range messages {
+		ids = append(ids, msg.MessageID)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestPurgeRespectsGroupRetention(t *testing.T) {
+	for name, tc := range map[string]struct {
+		defaultDays int
+		want        map[int64][]int64
+	}{
+		"default of 5 days": {5, map[int64][]int64{
+			defaultGroup: {1, 3},
+			shortGroup:   {1},
+			foreverGroup: {1, 3, 10},
+		}},
+		"no default": {0, map[int64][]int64{
+			defaultGroup: {1, 3, 10},
+			shortGroup:   {1},
+			foreverGroup: {1, 3, 10},
+		}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			store := openStore(t)
+			New(store, tc.defaultDays).purge(context.Background(), now)
+
+			for groupID, want := range tc.want {
+				if got := remaining(t, store, groupID); !slices.Equal(got, want) {
+					t.Errorf("group %d kept messages %v days old, want %v", groupID, got, want)
+				}
+			}
+		})
+	}
+}
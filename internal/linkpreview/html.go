@@ -0,0 +1,100 @@
+package linkpreview
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// minParagraphLength skips navigation and caption snippets when looking for readable text.
+const minParagraphLength = 80
+
+// parsePage extracts the title and a description of an HTML page. Open Graph tags
+// win over <title> and the meta description; without either, the first paragraphs
+// long enough to be article text are used.
+func parsePage(page []byte) (title, description string) {
+	doc, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return "", ""
+	}
+
+	var pageTitle, ogTitle, metaDescription, ogDescription string
+	var paragraphs []string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "script", "style", "noscript", "nav", "header", "footer", "aside":
+				return
+			case "title":
+				if pageTitle == "" {
+					pageTitle = textOf(n)
+				}
+			case "meta":
+				content := attr(n, "content")
+				switch strings.ToLower(attr(n, "property") + attr(n, "name")) {
+				case "og:title":
+					ogTitle = content
+				case "og:description":
+					ogDescription = content
+				case "description":
+					metaDescription = content
+				}
+			case "p":
+				if text := textOf(n); len(text) >= minParagraphLength && len(paragraphs) < 3 {
+					paragraphs = append(paragraphs, text)
+				}
+				return
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+
+	title = firstNonEmpty(ogTitle, pageTitle)
+	description = firstNonEmpty(ogDescription, metaDescription, strings.Join(paragraphs, " "))
+	return clean(title), clean(description)
+}
+
+// textOf returns the text inside a node with whitespace collapsed.
+func textOf(n *html.Node) string {
+	var sb strings.Builder
+	var collect func(n *html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			sb.WriteString(" ")
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			collect(child)
+		}
+	}
+	collect(n)
+	return clean(sb.String())
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, key) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// clean collapses whitespace, including newlines, which would break transcript lines.
+func clean(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,191 @@
+// Package linkpreview fetches the title and a short description of web pages shared
+// in a chat, so the summarizer knows what a link is about.
+package linkpreview
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+)
+
+// Preview is what is known about a linked page.
+type Preview struct {
+	URL         string
+	Title       string
+	Description string
+}
+
+// String renders the preview as "Title — description", either part being optional.
+func (p Preview) String() string {
+	switch {
+	case p.Title != "" && p.Description != "":
+		return p.Title + " — " + p.Description
+	case p.Title != "":
+		return p.Title
+	default:
+		return p.Description
+	}
+}
+
+// Fetcher downloads pages with size and time limits.
+type Fetcher struct {
+	HTTPClient *http.Client
+	// MaxBytes is how much of a page is read; the rest is ignored.
+	MaxBytes int64
+	// MaxDescription caps the length of descriptions, in characters.
+	MaxDescription int
+}
+
+// defaultMaxDescription keeps a preview to a couple of sentences.
+const defaultMaxDescription = 300
+
+// NewFetcher returns a Fetcher that gives up on a page after timeout and reads at most
+// maxBytes of it. Unless allowPrivate is set, it refuses to connect to loopback,
+// private and link-local addresses, so chat members cannot make the bot probe the
+// network it runs in.
+func NewFetcher(timeout time.Duration, maxBytes int64, allowPrivate bool) *Fetcher {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = refusePrivate
+	}
+	// No proxy: it would connect on our behalf and bypass the address check.
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+	}
+
+	return &Fetcher{
+		HTTPClient: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= 5 {
+					return fmt.Errorf("too many redirects")
+				}
+				return nil
+			},
+		},
+		MaxBytes:       maxBytes,
+		MaxDescription: defaultMaxDescription,
+	}
+}
+
+// reservedNetworks are ranges the net.IP predicates miss that are not publicly routable
+// either: "this network", carrier-grade NAT and benchmarking.
+var reservedNetworks = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("198.18.0.0/15"),
+}
+
+func mustParseCIDR(s string) *net.IPNet {
+	_, network, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
+
+// refusePrivate rejects connections to addresses that are not publicly routable.
+// It runs after name resolution, so it also covers DNS names and redirects.
+func refusePrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast() || isReserved(ip) {
+		return fmt.Errorf("refusing to connect to non-public address %s", host)
+	}
+	return nil
+}
+
+func isReserved(ip net.IP) bool {
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fetch downloads an HTML page and extracts its preview.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (Preview, error) {
+	preview := Preview{URL: rawURL}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return preview, err
+	}
+	req.Header.Set("User-Agent", "tldr-telegram-bot (link preview)")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.HTTPClient.Do(req)
+	if err != nil {
+		return preview, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return preview, fmt.Errorf("fetching %s: %s", rawURL, resp.Status)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return preview, fmt.Errorf("fetching %s: unsupported content type %q", rawURL, mediaType)
+	}
+
+	page, err := io.ReadAll(io.LimitReader(resp.Body, f.MaxBytes))
+	if err != nil {
+		return preview, fmt.Errorf("reading %s: %w", rawURL, err)
+	}
+
+	preview.Title, preview.Description = parsePage(page)
+	preview.Description = truncate(preview.Description, f.MaxDescription)
+	if preview.Title == "" && preview.Description == "" {
+		return preview, fmt.Errorf("no title or text found at %s", rawURL)
+	}
+	return preview, nil
+}
+
+var urlPattern = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"]+`)
+
+// ExtractURLs returns the distinct http(s) URLs in text, in order of appearance.
+func ExtractURLs(text string) []string {
+	seen := map[string]bool{}
+	var urls []string
+	for _, match := range urlPattern.FindAllString(text, -1) {
+		// Punctuation right after a link belongs to the sentence.
+		match = strings.TrimRight(match, ".,;:!?)]}'")
+		if u, err := url.Parse(match); err != nil || u.Host == "" {
+			continue
+		}
+		if !seen[match] {
+			seen[match] = true
+			urls = append(urls, match)
+		}
+	}
+	return urls
+}
+
+// truncate shortens s to at most max characters, cutting at a word boundary.
+func truncate(s string, max int) string {
+	if max <= 0 || utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)[:max]
+	cut := string(runes)
+	if i := strings.LastIndex(cut, " "); i > len(cut)/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimSpace(cut) + "…"
+}
@@ -0,0 +1,129 @@
+package linkpreview
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const paragraph = "This paragraph is long enough to count as article text rather than a navigation snippet or caption."
+
+func servePage(t *testing.T, page string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, page)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFetchExtractsTitleAndText(t *testing.T) {
+	server := servePage(t, `<html><head><title> The
+		Title </title></head><body><nav><p>`+paragraph+` (nav)</p></nav><p>Too short.</p><p>`+paragraph+`</p></body></html>`)
+
+	preview, err := NewFetcher(time.Second, 1<<20, true).Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if preview.Title != "The Title" {
+		t.Errorf("title = %q, want %q", preview.Title, "The Title")
+	}
+	if preview.Description != paragraph {
+		t.Errorf("description = %q, want %q", preview.Description, paragraph)
+	}
+}
+
+func TestFetchPrefersOpenGraph(t *testing.T) {
+	server := servePage(t, `<html><head><title>Page</title>
+		<meta property="og:title" content="OG title">
+		<meta name="description" content="Meta description">
+		<meta property="og:description" content="OG description">
+		</head><body><p>`+paragraph+`</p></body></html>`)
+
+	preview, err := NewFetcher(time.Second, 1<<20, true).Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if got := preview.String(); got != "OG title — OG description" {
+		t.Errorf("preview = %q, want %q", got, "OG title — OG description")
+	}
+}
+
+func TestFetchReadsAtMostMaxBytes(t *testing.T) {
+	head := `<html><head><title>Cut</title></head><body>`
+	server := servePage(t, head+strings.Repeat(" ", 1000)+`<p>`+paragraph+`</p></body></html>`)
+
+	preview, err := NewFetcher(time.Second, int64(len(head)+100), true).Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if preview.Title != "Cut" || preview.Description != "" {
+		t.Errorf("preview = %+v, want the title only", preview)
+	}
+}
+
+func TestFetchTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	start := time.Now()
+	_, err := NewFetcher(100*time.Millisecond, 1<<20, true).Fetch(context.Background(), server.URL)
+	if err == nil {
+		t.Fatal("Fetch succeeded, want a timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Fetch gave up after %s, want about 100ms", elapsed)
+	}
+}
+
+func TestFetchRefusesPrivateAddresses(t *testing.T) {
+	server := servePage(t, `<html><head><title>Internal</title></head></html>`)
+
+	_, err := NewFetcher(time.Second, 1<<20, false).Fetch(context.Background(), server.URL)
+	if err == nil || !strings.Contains(err.Error(), "non-public address") {
+		t.Fatalf("Fetch error = %v, want a refusal", err)
+	}
+}
+
+func TestRefusePrivate(t *testing.T) {
+	for address, refused := range map[string]bool{
+		"127.0.0.1:80":       true,
+		"10.1.2.3:80":        true,
+		"192.168.0.1:443":    true,
+		"169.254.169.254:80": true,
+		"0.0.0.0:80":         true,
+		"100.64.0.1:80":      true,
+		"198.19.255.1:80":    true,
+		"[::1]:80":           true,
+		"[fd00::1]:80":       true,
+		"93.184.216.34:443":  false,
+		"100.128.0.1:80":     false,
+		"[2606:4700::1]:80":  false,
+	} {
+		if err := refusePrivate("tcp", address, nil); (err != nil) != refused {
+			t.Errorf("refusePrivate(%s) = %v, want refused %v", address, err, refused)
+		}
+	}
+}
+
+func TestExtractURLs(t *testing.T) {
+	text := "See https://example.com/a. Also (https://example.com/b), https://example.com/a again! " +
+		"and http://example.org/path?q=1; not ftp://example.net or https://"
+	want := []string{"https://example.com/a", "https://example.com/b", "http://example.org/path?q=1"}
+	if got := ExtractURLs(text); !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractURLs = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,205 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// breakerThreshold is the number of consecutive failures that opens a provider's circuit.
+	breakerThreshold = 3
+	// breakerCooldown is how long an open circuit rejects calls before a single probe is let through.
+	breakerCooldown = time.Minute
+)
+
+// ChainEntry names a provider in a failover chain together with its per-call timeout.
+type ChainEntry struct {
+	Name    string
+	Timeout time.Duration
+}
+
+// ParseChainEntry parses "name" or "name:timeout" (e.g. "gemini:30s").
+// A zero Timeout means the chain default applies.
+func ParseChainEntry(spec string) (ChainEntry, error) {
+	name, timeout, hasTimeout := strings.Cut(strings.TrimSpace(spec), ":")
+	entry := ChainEntry{Name: strings.ToLower(strings.TrimSpace(name))}
+	if entry.Name == "" {
+		return entry, fmt.Errorf("empty provider name in %q", spec)
+	}
+	if hasTimeout {
+		d, err := time.ParseDuration(strings.TrimSpace(timeout))
+		if err != nil || d <= 0 {
+			return entry, fmt.Errorf("invalid timeout for provider %s: %q", entry.Name, timeout)
+		}
+		entry.Timeout = d
+	}
+	return entry, nil
+}
+
+type chainLink struct {
+	ChainEntry
+	summarizer Summarizer
+	breaker    *breaker
+}
+
+// Chain tries its providers in order until one of them produces a summary.
+// Providers whose circuit is open are skipped without being called.
+type Chain struct {
+	links []chainLink
+}
+
+// NewChain builds a failover chain from provider specs as accepted by ParseChainEntry.
+// Entries without an explicit timeout use defaultTimeout.
+func NewChain(specs []string, defaultTimeout time.Duration) (*Chain, error) {
+	chain := &Chain{}
+	for _, spec := range specs {
+		entry, err := ParseChainEntry(spec)
+		if err != nil {
+			return nil, err
+		}
+		if entry.Timeout == 0 {
+			entry.Timeout = defaultTimeout
+		}
+		summarizer, err := New(entry.Name)
+		if err != nil {
+			return nil, fmt.Errorf("provider %s: %w", entry.Name, err)
+		}
+		chain.links = append(chain.links, chainLink{
+			ChainEntry: entry,
+			summarizer: summarizer,
+			breaker:    breakerFor(entry.Name),
+		})
+	}
+	if len(chain.links) == 0 {
+		return nil, errors.New("no LLM providers configured")
+	}
+	return chain, nil
+}
+
+// Summarize implements Summarizer.
+func (c *Chain) Summarize(ctx context.Context, req Request) (string, error) {
+	summary, _, err := c.SummarizeWithProvider(ctx, req)
+	return summary, err
+}
+
+// SummarizeWithProvider runs the chain and also reports which provider served the summary.
+func (c *Chain) SummarizeWithProvider(ctx context.Context, req Request) (string, string, error) {
+	return c.SummarizeStream(ctx, req, nil)
+}
+
+// SummarizeStream is like SummarizeWithProvider but streams progress from providers that
+// support it. When a provider fails mid-stream, the next one starts over, so onProgress
+// always receives the complete text generated so far by the current provider.
+func (c *Chain) SummarizeStream(ctx context.Context, req Request, onProgress func(text string)) (string, string, error) {
+	var errs []error
+	for _, link := range c.links {
+		if !link.breaker.allow() {
+			errs = append(errs, fmt.Errorf("%s: circuit open", link.Name))
+			continue
+		}
+
+		summary, err := link.summarize(ctx, req, onProgress)
+		if ctx.Err() != nil {
+			// The caller gave up; this says nothing about the provider's health.
+			link.breaker.release()
+			return "", "", ctx.Err()
+		}
+		if err == nil && strings.TrimSpace(summary) == "" {
+			err = errors.New("empty summary")
+		}
+		if err != nil {
+			link.breaker.failure()
+			log.Printf("LLM provider %s failed: %v", link.Name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", link.Name, err))
+			continue
+		}
+
+		link.breaker.success()
+		return summary, link.Name, nil
+	}
+	return "", "", fmt.Errorf("all LLM providers failed: %w", errors.Join(errs...))
+}
+
+func (l chainLink) summarize(ctx context.Context, req Request, onProgress func(text string)) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, l.Timeout)
+	defer cancel()
+
+	if streamer, ok := l.summarizer.(StreamSummarizer); ok && onProgress != nil {
+		return streamer.SummarizeStream(ctx, req, onProgress)
+	}
+	return l.summarizer.Summarize(ctx, req)
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = map[string]*breaker{}
+)
+
+// breakerFor returns the process-wide circuit breaker of a provider, so its
+// state survives across chains built for individual requests.
+func breakerFor(name string) *breaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	b, ok := breakers[name]
+	if !ok {
+		b = &breaker{}
+		breakers[name] = b
+	}
+	return b
+}
+
+// breaker is a consecutive-failure circuit breaker. Once open, it lets a
+// single probe through after the cooldown and closes again if it succeeds.
+type breaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < breakerThreshold {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.probing = false
+}
+
+func (b *breaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.failures >= breakerThreshold {
+		b.openUntil = time.Now().Add(breakerCooldown)
+	}
+}
+
+// release ends a probe without a verdict, so the next call may probe again.
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
@@ -0,0 +1,189 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	// Each step acts on the breaker; "allow" steps check its answer.
+	type step struct {
+		action string
+		allow  bool
+	}
+	for name, steps := range map[string][]step{
+		"closed below the threshold": {
+			{"fail", false}, {"fail", false}, {"allow", true},
+		},
+		"opens after 3 failures": {
+			{"fail", false}, {"fail", false}, {"fail", false}, {"allow", false}, {"allow", false},
+		},
+		"success resets the count": {
+			{"fail", false}, {"fail", false}, {"succeed", false}, {"fail", false}, {"allow", true},
+		},
+		"one probe after the cooldown": {
+			{"fail", false}, {"fail", false}, {"fail", false}, {"cool down", false},
+			{"allow", true}, {"allow", false},
+		},
+		"successful probe closes": {
+			{"fail", false}, {"fail", false}, {"fail", false}, {"cool down", false},
+			{"allow", true}, {"succeed", false}, {"allow", true}, {"allow", true},
+		},
+		"failed probe reopens": {
+			{"fail", false}, {"fail", false}, {"fail", false}, {"cool down", false},
+			{"allow", true}, {"fail", false}, {"allow", false},
+		},
+		"released probe may be retried": {
+			{"fail", false}, {"fail", false}, {"fail", false}, {"cool down", false},
+			{"allow", true}, {"release", false}, {"allow", true}, {"allow", false},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			b := &breaker{}
+			for i, s := range steps {
+				switch s.action {
+				case "allow":
+					if got := b.allow(); got != s.allow {
+						t.Fatalf("step %d: allow() = %v, want %v", i, got, s.allow)
+					}
+				case "fail":
+					b.failure()
+				case "succeed":
+					b.success()
+				case "release":
+					b.release()
+				case "cool down":
+					b.openUntil = time.Now().Add(-time.Second)
+				}
+			}
+		})
+	}
+}
+
+// fakeSummarizer returns summary and err, or blocks until the context is done
+// when block is set.
+type fakeSummarizer struct {
+	summary string
+	err     error
+	block   bool
+	calls   int
+}
+
+func (f *fakeSummarizer) Summarize(ctx context.Context, req Request) (string, error) {
+	f.calls++
+	if f.block {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	return f.summary, f.err
+}
+
+// testChain builds a chain over the given providers, each with a fresh breaker.
+func testChain(names []string, summarizers ...Summarizer) *Chain {
+	chain := &Chain{}
+	for i, s := range summarizers {
+		chain.links = append(chain.links, chainLink{
+			ChainEntry: ChainEntry{Name: names[i], Timeout: time.Second},
+			summarizer: s,
+			breaker:    &breaker{},
+		})
+	}
+	return chain
+}
+
+func TestChainFailover(t *testing.T) {
+	failing := errors.New("model overloaded")
+	for name, tc := range map[string]struct {
+		providers []*fakeSummarizer
+		open      []bool
+		summary   string
+		provider  string
+		calls     []int
+		failures  []int
+	}{
+		"first provider serves": {
+			providers: []*fakeSummarizer{{summary: "a says hi"}, {summary: "b says hi"}},
+			summary:   "a says hi", provider: "a", calls: []int{1, 0}, failures: []int{0, 0},
+		},
+		"error fails over": {
+			providers: []*fakeSummarizer{{err: failing}, {summary: "b says hi"}},
+			summary:   "b says hi", provider: "b", calls: []int{1, 1}, failures: []int{1, 0},
+		},
+		"empty summary fails over": {
+			providers: []*fakeSummarizer{{summary: " \n"}, {summary: "b says hi"}},
+			summary:   "b says hi", provider: "b", calls: []int{1, 1}, failures: []int{1, 0},
+		},
+		"open circuit is skipped": {
+			providers: []*fakeSummarizer{{summary: "a says hi"}, {err: failing}, {summary: "c says hi"}},
+			open:      []bool{true, false, false},
+			summary:   "c says hi", provider: "c", calls: []int{0, 1, 1}, failures: []int{breakerThreshold, 1, 0},
+		},
+		"timeout fails over": {
+			providers: []*fakeSummarizer{{block: true}, {summary: "b says hi"}},
+			summary:   "b says hi", provider: "b", calls: []int{1, 1}, failures: []int{1, 0},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var summarizers []Summarizer
+			for _, p := range tc.providers {
+				summarizers = append(summarizers, p)
+			}
+			chain := testChain([]string{"a", "b", "c"}, summarizers...)
+			chain.links[0].Timeout = 10 * time.Millisecond
+			for i, open := range tc.open {
+				if open {
+					chain.links[i].breaker = &breaker{failures: breakerThreshold, openUntil: time.Now().Add(time.Minute)}
+				}
+			}
+
+			summary, provider, err := chain.SummarizeWithProvider(context.Background(), Request{Text: "hi"})
+			if err != nil {
+				t.Fatalf("SummarizeWithProvider: %v", err)
+			}
+			if summary != tc.summary || provider != tc.provider {
+				t.Errorf("got %q from %s, want %q from %s", summary, provider, tc.summary, tc.provider)
+			}
+			for i, p := range tc.providers {
+				if p.calls != tc.calls[i] {
+					t.Errorf("provider %s called %d times, want %d", chain.links[i].Name, p.calls, tc.calls[i])
+				}
+				if got := chain.links[i].breaker.failures; got != tc.failures[i] {
+					t.Errorf("provider %s has %d failures, want %d", chain.links[i].Name, got, tc.failures[i])
+				}
+			}
+		})
+	}
+}
+
+func TestChainAllFail(t *testing.T) {
+	chain := testChain([]string{"a", "b"}, &fakeSummarizer{err: errors.New("down")}, &fakeSummarizer{summary: ""})
+	if _, _, err := chain.SummarizeWithProvider(context.Background(), Request{Text: "hi"}); err == nil {
+		t.Fatal("SummarizeWithProvider succeeded, want an error")
+	}
+}
+
+func TestChainCancelReleasesProbe(t *testing.T) {
+	probed := &fakeSummarizer{block: true}
+	next := &fakeSummarizer{summary: "b says hi"}
+	chain := testChain([]string{"a", "b"}, probed, next)
+	// a's circuit has cooled down, so this call is its probe.
+	chain.links[0].breaker = &breaker{failures: breakerThreshold, openUntil: time.Now().Add(-time.Second)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := chain.SummarizeWithProvider(ctx, Request{Text: "hi"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("SummarizeWithProvider error = %v, want the context's", err)
+	}
+	if next.calls != 0 {
+		t.Errorf("next provider called %d times after the caller gave up, want 0", next.calls)
+	}
+	b := chain.links[0].breaker
+	if b.failures != breakerThreshold || b.probing {
+		t.Errorf("breaker = %d failures, probing %v; want the failures unchanged and the probe released", b.failures, b.probing)
+	}
+	if !b.allow() {
+		t.Error("allow() = false after the probe was released, want a new probe")
+	}
+}
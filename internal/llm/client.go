@@ -5,150 +5,139 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
-	"time"
+	"strings"
 
 	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
 
+func init() {
+	Register("ollama", func() (Summarizer, error) { return NewOllamaClient() })
+	Register("gemini", func() (Summarizer, error) { return NewGeminiClient() })
+}
+
 type OllamaClient struct {
 	BaseURL    string
 	HTTPClient *http.Client
 	ModelName  string
+	// VisionModelName describes images; empty uses ModelName.
+	VisionModelName string
 }
 
-func NewOllamaClient() *OllamaClient {
-	return &OllamaClient{
-		BaseURL:    os.Getenv("OLLAMA_API_URL"),
-		HTTPClient: &http.Client{Timeout: 10 * time.Second},
-		ModelName:  os.Getenv("OLLAMA_MODEL"),
-	}
-}
-
-func (c *OllamaClient) GenerateSummary(prompt string) (string, error) {
-	requestBody, err := json.Marshal(map[string]string{
-		"model":  c.ModelName,
-		"prompt": prompt,
-	})
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request body: %w", err)
-	}
-
-	resp, err := c.HTTPClient.Post(c.BaseURL, "application/json", bytes.NewBuffer(requestBody))
-	if err != nil {
-		return "", fmt.Errorf("failed to send request to Ollama: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("received non-200 response from Ollama: %s", resp.Status)
-	}
-
-	var response struct {
-		Summary string `json:"summary"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return "", fmt.Errorf("failed to decode response from Ollama: %w", err)
+func NewOllamaClient() (*OllamaClient, error) {
+	model := os.Getenv("OLLAMA_MODEL")
+	if model == "" {
+		return nil, fmt.Errorf("OLLAMA_MODEL environment variable is not set")
 	}
 
-	return response.Summary, nil
-}
-
-type GeminiClient struct {
-	BaseURL    string
-	HTTPClient *http.Client
-	ModelName  string
-	APIkey     string
+	return &OllamaClient{
+		BaseURL:         os.Getenv("OLLAMA_API_URL"),
+		HTTPClient:      &http.Client{},
+		ModelName:       model,
+		VisionModelName: os.Getenv("OLLAMA_VISION_MODEL"),
+	}, nil
 }
 
-func NewGeminiClient() *GeminiClient {
-	return &GeminiClient{
-		// In this case, BaseURL expects the base endpoint without model string and query parameters.
-		// For example: "https://generativelanguage.googleapis.com/v1beta/models"
-		BaseURL:    os.Getenv("GEMINI_API_URL"),
-		HTTPClient: &http.Client{Timeout: 10 * time.Second},
-		ModelName:  os.Getenv("GEMINI_MODEL"),
-		APIkey:     os.Getenv("GEMINI_API_KEY"),
-	}
+// Summarize sends a request to the Ollama LLM server and waits until done is true.
+func (c *OllamaClient) Summarize(ctx context.Context, req Request) (string, error) {
+	return c.SummarizeStream(ctx, req, nil)
 }
 
-func GenerateSummaryGemini(prompt string) (string, error) {
-	client := NewGeminiClient()
-
-	// Construct the full URL using the environment variables.
-	// GEMINI_API_URL should be "https://generativelanguage.googleapis.com/v1beta/models"
-	// GEMINI_MODEL should be, e.g., "gemini-2.0-flash"
-	url := fmt.Sprintf("%s/%s:generateContent?key=%s", client.BaseURL, client.ModelName, client.APIkey)
-
-	// Build request body based on the curl example.
-	payload := map[string]interface{}{
-		"contents": []map[string]interface{}{
-			{
-				"parts": []map[string]string{
-					{"text": prompt},
-				},
-			},
-		},
-	}
-
-	requestBody, err := json.Marshal(payload)
+// SummarizeStream asks Ollama for a streamed response and reports the text generated so far
+// after every chunk.
+func (c *OllamaClient) SummarizeStream(ctx context.Context, req Request, onProgress func(text string)) (string, error) {
+	requestBody, err := json.Marshal(map[string]interface{}{
+		"model":  c.ModelName,
+		"prompt": req.prompt(),
+		"stream": true,
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request body: %w", err)
+		return "", fmt.Errorf("failed to marshal request body: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(requestBody))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL, bytes.NewBuffer(requestBody))
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
-	req.Header.Set("Content-Type", "application/json")
-	// Remove the following header if the API key is supposed to be in the URL.
-	// req.Header.Set("Authorization", "Bearer "+client.APIkey)
+	httpReq.Header.Set("Content-Type", "application/json")
 
-	resp, err := client.HTTPClient.Do(req)
+	resp, err := c.HTTPClient.Do(httpReq)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request to Gemini: %w", err)
+		return "", fmt.Errorf("failed to send request to Ollama API: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("received non-200 response from Gemini: %s", resp.Status)
-	}
-
-	// Assuming the response returns a candidates array with an "output" field.
-	var response struct {
-		Candidates []struct {
-			Output string `json:"output"`
-		} `json:"candidates"`
+		return "", fmt.Errorf("received non-200 response from Ollama API: %s", resp.Status)
+	}
+
+	// The streamed body is a sequence of JSON objects, one per generated chunk.
+	var summary strings.Builder
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var chunk struct {
+			Response string `json:"response"`
+			Done     bool   `json:"done"`
+			Error    string `json:"error"`
+		}
+		if err := decoder.Decode(&chunk); err != nil {
+			if err == io.EOF {
+				return "", fmt.Errorf("stream ended before completion")
+			}
+			return "", fmt.Errorf("failed to decode response: %v", err)
+		}
+		if chunk.Error != "" {
+			return "", fmt.Errorf("ollama error: %s", chunk.Error)
+		}
+
+		summary.WriteString(chunk.Response)
+		if onProgress != nil && chunk.Response != "" {
+			onProgress(summary.String())
+		}
+		if chunk.Done {
+			return summary.String(), nil
+		}
 	}
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return "", fmt.Errorf("failed to decode response from Gemini: %w", err)
-	}
+type GeminiClient struct {
+	ModelName string
+	APIkey    string
+}
 
-	if len(response.Candidates) == 0 {
-		return "", fmt.Errorf("no summary candidates returned")
+func NewGeminiClient() (*GeminiClient, error) {
+	apiKey := os.Getenv("GEMINI_API_KEY")
+	if apiKey == "" {
+		return nil, fmt.Errorf("GEMINI_API_KEY environment variable is not set")
 	}
 
-	return response.Candidates[0].Output, nil
+	return &GeminiClient{
+		ModelName: os.Getenv("GEMINI_MODEL"),
+		APIkey:    apiKey,
+	}, nil
 }
 
-// SummarizeGemini summarizes text using the Gemini API
-func SummarizeGemini(text string, lang string) (string, error) {
-	ctx := context.Background()
-	apiKey := os.Getenv("GEMINI_API_KEY")
+// Summarize summarizes text using the Gemini API
+func (c *GeminiClient) Summarize(ctx context.Context, req Request) (string, error) {
+	return c.SummarizeStream(ctx, req, nil)
+}
 
+// SummarizeStream summarizes text using the Gemini streaming API, reporting the text
+// generated so far after every chunk.
+func (c *GeminiClient) SummarizeStream(ctx context.Context, req Request, onProgress func(text string)) (string, error) {
 	// Initialize the client
-	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+	client, err := genai.NewClient(ctx, option.WithAPIKey(c.APIkey))
 	if err != nil {
 		return "", fmt.Errorf("failed to create Gemini client: %v", err)
 	}
 	defer client.Close()
 
 	// Create the model
-	model := client.GenerativeModel(os.Getenv("GEMINI_MODEL"))
+	model := client.GenerativeModel(c.ModelName)
 
 	// Configure parameters for better summarization
 	model.SetTemperature(0.2)
@@ -156,29 +145,34 @@ func SummarizeGemini(text string, lang string) (string, error) {
 	model.SetTopP(0.95)
 	model.SetMaxOutputTokens(1024)
 
-	// Set a timeout for the context
-	ctxWithTimeout, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
-
-	// Create prompt based on language
-	prompt := constructPrompt(text, lang)
-
-	// Generate content
-	resp, err := model.GenerateContent(ctxWithTimeout, genai.Text(prompt))
-	if err != nil {
-		return "", fmt.Errorf("failed to generate content: %v", err)
-	}
-
-	// Extract the text from the response
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
+	// Generate content; the caller's context bounds how long it may take
+	var summary strings.Builder
+	iter := model.GenerateContentStream(ctx, genai.Text(req.prompt()))
+	for {
+		resp, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to generate content: %v", err)
+		}
+
+		// Extract the text from the response
+		if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+			continue
+		}
+		for _, part := range resp.Candidates[0].Content.Parts {
+			if text, ok := part.(genai.Text); ok {
+				summary.WriteString(string(text))
+			}
+		}
+		if onProgress != nil {
+			onProgress(summary.String())
+		}
+	}
+
+	if summary.Len() == 0 {
 		return "", fmt.Errorf("no response generated")
 	}
-
-	// Get the text from the first candidate's first part
-	summary, ok := resp.Candidates[0].Content.Parts[0].(genai.Text)
-	if !ok {
-		return "", fmt.Errorf("unexpected response format")
-	}
-
-	return string(summary), nil
+	return summary.String(), nil
 }
@@ -0,0 +1,79 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeOllama serves the generate endpoint with body, after checking the request the
+// client sent.
+func fakeOllama(t *testing.T, status int, body string) *OllamaClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model  string `json:"model"`
+			Prompt string `json:"prompt"`
+			Stream bool   `json:"stream"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		if req.Model != "test-model" || !req.Stream || !strings.Contains(req.Prompt, "Alice: hello") {
+			t.Errorf("request = %+v, want a streamed prompt for test-model", req)
+		}
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+
+	return &OllamaClient{BaseURL: server.URL + "/api/generate", HTTPClient: server.Client(), ModelName: "test-model"}
+}
+
+func TestOllamaSummarizeStream(t *testing.T) {
+	client := fakeOllama(t, http.StatusOK, strings.Join([]string{
+		`{"response":"Alice ","done":false}`,
+		`{"response":"","done":false}`,
+		`{"response":"said hello.","done":false}`,
+		`{"response":"","done":true,"total_duration":123}`,
+	}, "\n"))
+
+	var progress []string
+	summary, err := client.SummarizeStream(context.Background(), testRequest, func(text string) {
+		progress = append(progress, text)
+	})
+	if err != nil {
+		t.Fatalf("SummarizeStream: %v", err)
+	}
+	if summary != "Alice said hello." {
+		t.Errorf("summary = %q, want %q", summary, "Alice said hello.")
+	}
+	if want := []string{"Alice ", "Alice said hello."}; !reflect.DeepEqual(progress, want) {
+		t.Errorf("progress = %q, want %q", progress, want)
+	}
+}
+
+func TestOllamaSummarizeStreamErrors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		status int
+		body   string
+	}{
+		"error chunk": {http.StatusOK, `{"response":"Alice","done":false}` + "\n" + `{"error":"model not found"}`},
+		"truncated":   {http.StatusOK, `{"response":"Alice","done":false}`},
+		"invalid":     {http.StatusOK, `{"response":`},
+		"empty":       {http.StatusOK, ""},
+		"HTTP error":  {http.StatusInternalServerError, `{"error":"out of memory"}`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client := fakeOllama(t, tc.status, tc.body)
+			if _, err := client.Summarize(context.Background(), testRequest); err == nil {
+				t.Fatal("Summarize succeeded, want an error")
+			}
+		})
+	}
+}
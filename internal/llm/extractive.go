@@ -0,0 +1,113 @@
+package llm
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+func init() {
+	Register("extractive", func() (Summarizer, error) { return ExtractiveSummarizer{}, nil })
+}
+
+// extractiveMaxLines caps how many transcript lines the extractive summary keeps.
+const extractiveMaxLines = 5
+
+// ExtractiveSummarizer picks the most representative transcript lines without
+// calling any model. It is meant as the last link of a failover chain.
+type ExtractiveSummarizer struct{}
+
+// Summarize scores each line by the document frequency of its words and returns
+// the best ones in their original order.
+func (ExtractiveSummarizer) Summarize(_ context.Context, req Request) (string, error) {
+	var lines []string
+	for _, line := range strings.Split(req.Text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return "", nil
+	}
+
+	frequency := map[string]int{}
+	lineWords := make([][]string, len(lines))
+	for i, line := range lines {
+		lineWords[i] = significantWords(messageBody(line))
+		for _, word := range lineWords[i] {
+			frequency[word]++
+		}
+	}
+
+	type scored struct {
+		index int
+		score float64
+	}
+	scores := make([]scored, len(lines))
+	for i, words := range lineWords {
+		var total float64
+		for _, word := range words {
+			total += float64(frequency[word])
+		}
+		if len(words) > 0 {
+			total /= math.Sqrt(float64(len(words)))
+		}
+		scores[i] = scored{index: i, score: total}
+	}
+	sort.SliceStable(scores, func(a, b int) bool { return scores[a].score > scores[b].score })
+
+	keep := extractiveMaxLines
+	if len(scores) < keep {
+		keep = len(scores)
+	}
+	picked := make([]int, 0, keep)
+	for _, s := range scores[:keep] {
+		picked = append(picked, s.index)
+	}
+	sort.Ints(picked)
+
+	var sb strings.Builder
+	sb.WriteString(extractiveHeader(req.Lang))
+	for _, i := range picked {
+		sb.WriteString("\n• ")
+		sb.WriteString(lines[i])
+	}
+	return sb.String(), nil
+}
+
+// messageBody strips the "Sender: " prefix produced by the transcript formatter.
+func messageBody(line string) string {
+	if _, body, ok := strings.Cut(line, ": "); ok {
+		return body
+	}
+	return line
+}
+
+func significantWords(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	seen := map[string]bool{}
+	words := fields[:0]
+	for _, f := range fields {
+		if len([]rune(f)) < 4 || seen[f] {
+			continue
+		}
+		seen[f] = true
+		words = append(words, f)
+	}
+	return words
+}
+
+func extractiveHeader(lang string) string {
+	switch lang {
+	case "pt":
+		return "Mensagens principais (resumo extrativo, nenhum modelo disponível):"
+	case "es":
+		return "Mensajes principales (resumen extractivo, ningún modelo disponible):"
+	default:
+		return "Key messages (extractive summary, no model available):"
+	}
+}
@@ -0,0 +1,118 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	// charsPerToken is the rough ratio used to estimate token counts without a tokenizer.
+	charsPerToken = 4
+	// reservedTokens leaves room in the context window for the prompt template and the answer.
+	reservedTokens = 1024 + 256
+	// minInputTokens keeps chunks useful even for very small context windows.
+	minInputTokens = 256
+	// maxReduceDepth stops combining when partial summaries do not shrink.
+	maxReduceDepth = 4
+)
+
+// EstimateTokens approximates the number of tokens in s.
+func EstimateTokens(s string) int {
+	return (len([]rune(s)) + charsPerToken - 1) / charsPerToken
+}
+
+// InputBudget returns how many transcript tokens fit in a model with the given context window.
+func InputBudget(contextTokens int) int {
+	if budget := contextTokens - reservedTokens; budget > minInputTokens {
+		return budget
+	}
+	return minInputTokens
+}
+
+// ChunkLines groups consecutive lines into chunks whose estimated size stays within budget tokens.
+// A single line larger than the budget is truncated so it fits in a chunk on its own.
+func ChunkLines(lines []string, budget int) []string {
+	var chunks []string
+	var current strings.Builder
+	currentTokens := 0
+
+	for _, line := range lines {
+		tokens := EstimateTokens(line) + 1 // account for the newline
+		if tokens > budget {
+			line = string([]rune(line)[:(budget-1)*charsPerToken])
+			tokens = budget
+		}
+		if currentTokens+tokens > budget && current.Len() > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+			currentTokens = 0
+		}
+		current.WriteString(line)
+		current.WriteByte('\n')
+		currentTokens += tokens
+	}
+	if current.Len() > 0 {
+		chunks = append(chunks, current.String())
+	}
+	return chunks
+}
+
+// SummarizeLong summarizes a transcript that may not fit in the model context.
+// Lines are split into chunks of at most budget tokens, each chunk is summarized,
+// and the partial summaries are combined (recursively if needed) into one.
+// The returned provider lists every backend that contributed. Only the final step
+// applies the style and is streamed to onProgress, which may be nil.
+func (c *Chain) SummarizeLong(ctx context.Context, lines []string, lang, style string, budget int, onProgress func(text string)) (string, string, error) {
+	used := &providerSet{}
+	chunks := ChunkLines(lines, budget)
+	if len(chunks) == 0 {
+		return "", "", fmt.Errorf("nothing to summarize")
+	}
+	task := TaskSummarize
+
+	for depth := 0; ; depth++ {
+		if len(chunks) == 1 {
+			summary, provider, err := c.SummarizeStream(ctx, Request{Text: chunks[0], Lang: lang, Task: task, Style: style}, onProgress)
+			if err != nil {
+				return "", "", err
+			}
+			used.add(provider)
+			return summary, used.String(), nil
+		}
+		if depth == maxReduceDepth {
+			return "", "", fmt.Errorf("partial summaries still span %d chunks after %d rounds", len(chunks), depth)
+		}
+
+		partials := make([]string, 0, len(chunks))
+		for i, chunk := range chunks {
+			summary, provider, err := c.SummarizeWithProvider(ctx, Request{Text: chunk, Lang: lang, Task: task})
+			if err != nil {
+				return "", "", fmt.Errorf("chunk %d/%d: %w", i+1, len(chunks), err)
+			}
+			used.add(provider)
+			partials = append(partials, strings.TrimSpace(summary))
+		}
+
+		chunks = ChunkLines(partials, budget)
+		task = TaskCombine
+	}
+}
+
+// providerSet keeps provider names in first-use order.
+type providerSet struct {
+	names []string
+}
+
+func (p *providerSet) add(name string) {
+	for _, n := range p.names {
+		if n == name {
+			return
+		}
+	}
+	p.names = append(p.names, name)
+}
+
+func (p *providerSet) String() string {
+	return strings.Join(p.names, ", ")
+}
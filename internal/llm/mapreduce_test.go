@@ -0,0 +1,131 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChunkLines(t *testing.T) {
+	for name, tc := range map[string]struct {
+		lines  []string
+		budget int
+		want   []string
+	}{
+		"empty": {nil, 4, nil},
+		// "abcd" is one token, plus one for the newline.
+		"exactly at the budget": {[]string{"abcd", "abcd"}, 4, []string{"abcd\nabcd\n"}},
+		"one token over":        {[]string{"abcd", "abcd", "a"}, 4, []string{"abcd\nabcd\n", "a\n"}},
+		"long line is truncated": {
+			[]string{"short", strings.Repeat("x", 40), "after"}, 4,
+			[]string{"short\n", strings.Repeat("x", 12) + "\n", "after\n"},
+		},
+		"multibyte runes": {[]string{"ééééé"}, 2, []string{"éééé\n"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got := ChunkLines(tc.lines, tc.budget)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ChunkLines = %q, want %q", got, tc.want)
+			}
+			for _, chunk := range got {
+				if tokens := EstimateTokens(chunk); tokens > tc.budget {
+					t.Errorf("chunk %q is %d tokens, over the budget of %d", chunk, tokens, tc.budget)
+				}
+			}
+		})
+	}
+}
+
+// recordingSummarizer records its requests and answers them with answer.
+type recordingSummarizer struct {
+	requests []Request
+	answer   func(req Request) (string, error)
+}
+
+func (r *recordingSummarizer) Summarize(ctx context.Context, req Request) (string, error) {
+	r.requests = append(r.requests, req)
+	return r.answer(req)
+}
+
+func TestSummarizeLongSingleChunk(t *testing.T) {
+	model := &recordingSummarizer{answer: func(req Request) (string, error) { return "summary", nil }}
+	chain := testChain([]string{"a"}, model)
+
+	summary, provider, err := chain.SummarizeLong(context.Background(), []string{"Alice: hi", "Bob: hello"}, "en", "bullets", 100, nil)
+	if err != nil {
+		t.Fatalf("SummarizeLong: %v", err)
+	}
+	if summary != "summary" || provider != "a" {
+		t.Errorf("got %q from %q, want %q from %q", summary, provider, "summary", "a")
+	}
+	want := []Request{{Text: "Alice: hi\nBob: hello\n", Lang: "en", Task: TaskSummarize, Style: "bullets"}}
+	if !reflect.DeepEqual(model.requests, want) {
+		t.Errorf("requests = %+v, want %+v", model.requests, want)
+	}
+}
+
+func TestSummarizeLongCombinesInRounds(t *testing.T) {
+	// Eight two-token lines make four chunks of a four-token budget. Their four
+	// partial summaries make two chunks, combined into two more, then into one.
+	lines := []string{"l1", "l2", "l3", "l4", "l5", "l6", "l7", "l8"}
+	first := &recordingSummarizer{answer: func(req Request) (string, error) {
+		if req.Task == TaskCombine {
+			return "", errors.New("context too long")
+		}
+		return "p", nil
+	}}
+	second := &recordingSummarizer{answer: func(req Request) (string, error) { return "c", nil }}
+	chain := testChain([]string{"a", "b"}, first, second)
+
+	summary, provider, err := chain.SummarizeLong(context.Background(), lines, "en", "bullets", 4, nil)
+	if err != nil {
+		t.Fatalf("SummarizeLong: %v", err)
+	}
+	if summary != "c" || provider != "a, b" {
+		t.Errorf("got %q from %q, want %q from %q", summary, provider, "c", "a, b")
+	}
+
+	var texts []string
+	for _, req := range first.requests {
+		if req.Task == TaskSummarize {
+			texts = append(texts, req.Text)
+		}
+	}
+	if want := []string{"l1\nl2\n", "l3\nl4\n", "l5\nl6\n", "l7\nl8\n"}; !reflect.DeepEqual(texts, want) {
+		t.Errorf("chunks summarized = %q, want %q", texts, want)
+	}
+	// a failed every combine, so b served the two partial combines and the final one.
+	if len(second.requests) != 3 {
+		t.Fatalf("b got %d requests, want 3", len(second.requests))
+	}
+	for i, req := range second.requests {
+		final := i == len(second.requests)-1
+		if req.Task != TaskCombine || (req.Style != "") == !final {
+			t.Errorf("request %d = %+v, want a combine styled only if it is the final one", i, req)
+		}
+	}
+}
+
+func TestSummarizeLongGivesUpWhenSummariesDoNotShrink(t *testing.T) {
+	model := &recordingSummarizer{answer: func(req Request) (string, error) {
+		return strings.Repeat("verbose ", 10), nil
+	}}
+	chain := testChain([]string{"a"}, model)
+
+	_, _, err := chain.SummarizeLong(context.Background(), []string{"l1", "l2", "l3", "l4"}, "en", "", 2, nil)
+	if err == nil || !strings.Contains(err.Error(), "rounds") {
+		t.Fatalf("SummarizeLong error = %v, want it to give up", err)
+	}
+	if want := 4 * maxReduceDepth; len(model.requests) != want {
+		t.Errorf("made %d requests, want %d", len(model.requests), want)
+	}
+}
+
+func TestSummarizeLongNothingToSummarize(t *testing.T) {
+	chain := testChain([]string{"a"}, &fakeSummarizer{summary: "unused"})
+	if _, _, err := chain.SummarizeLong(context.Background(), nil, "en", "", 100, nil); err == nil {
+		t.Fatal("SummarizeLong succeeded, want an error")
+	}
+}
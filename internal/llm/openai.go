@@ -0,0 +1,182 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+func init() {
+	Register("openai", func() (Summarizer, error) { return NewOpenAIClient() })
+}
+
+// systemPrompt is sent as the system message to chat-completion backends.
+const systemPrompt = "You summarize Telegram group conversations. Be concise, keep the important decisions, questions and who said what."
+
+// OpenAIClient talks to any server implementing the OpenAI /v1/chat/completions
+// protocol, such as vLLM, llama.cpp or the OpenAI API itself.
+type OpenAIClient struct {
+	// BaseURL is the API root including the version, e.g. "http://localhost:8000/v1".
+	BaseURL    string
+	HTTPClient *http.Client
+	ModelName  string
+	// APIKey is sent as a bearer token when set.
+	APIKey string
+}
+
+func NewOpenAIClient() (*OpenAIClient, error) {
+	baseURL := os.Getenv("OPENAI_API_URL")
+	if baseURL == "" {
+		return nil, fmt.Errorf("OPENAI_API_URL environment variable is not set")
+	}
+	model := os.Getenv("OPENAI_MODEL")
+	if model == "" {
+		return nil, fmt.Errorf("OPENAI_MODEL environment variable is not set")
+	}
+
+	return &OpenAIClient{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: &http.Client{},
+		ModelName:  model,
+		APIKey:     os.Getenv("OPENAI_API_KEY"),
+	}, nil
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatCompletionRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Temperature float64       `json:"temperature"`
+	MaxTokens   int           `json:"max_tokens,omitempty"`
+	Stream      bool          `json:"stream,omitempty"`
+}
+
+type chatCompletionResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// chatCompletionChunk is one server-sent event of a streamed completion.
+type chatCompletionChunk struct {
+	Choices []struct {
+		Delta chatMessage `json:"delta"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Summarize sends the transcript to the chat-completions endpoint and returns the first choice.
+func (c *OpenAIClient) Summarize(ctx context.Context, req Request) (string, error) {
+	resp, err := c.post(ctx, req, false)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var response chatCompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", fmt.Errorf("failed to decode response from OpenAI-compatible API: %w", err)
+	}
+
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("no summary choices returned")
+	}
+
+	return strings.TrimSpace(response.Choices[0].Message.Content), nil
+}
+
+// SummarizeStream asks for a streamed completion and reports the text generated so far
+// after every chunk.
+func (c *OpenAIClient) SummarizeStream(ctx context.Context, req Request, onProgress func(text string)) (string, error) {
+	resp, err := c.post(ctx, req, true)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	// The streamed body is a sequence of "data: {chunk}" events ending with "data: [DONE]".
+	var summary strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			return strings.TrimSpace(summary.String()), nil
+		}
+
+		var chunk chatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return "", fmt.Errorf("failed to decode response from OpenAI-compatible API: %w", err)
+		}
+		if chunk.Error != nil {
+			return "", fmt.Errorf("OpenAI-compatible API error: %s", chunk.Error.Message)
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+
+		summary.WriteString(chunk.Choices[0].Delta.Content)
+		if onProgress != nil {
+			onProgress(summary.String())
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read response from OpenAI-compatible API: %w", err)
+	}
+	return "", fmt.Errorf("stream ended before completion")
+}
+
+// post sends a chat-completion request for req and returns the response once it is
+// known to be successful.
+func (c *OpenAIClient) post(ctx context.Context, req Request, stream bool) (*http.Response, error) {
+	requestBody, err := json.Marshal(chatCompletionRequest{
+		Model: c.ModelName,
+		Messages: []chatMessage{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: req.prompt()},
+		},
+		Temperature: 0.2,
+		MaxTokens:   1024,
+		Stream:      stream,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/chat/completions", bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	resp, err := c.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request to OpenAI-compatible API: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("received non-200 response from OpenAI-compatible API: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
@@ -0,0 +1,136 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeOpenAI serves /v1/chat/completions with handle, after checking the request
+// the client sent.
+func fakeOpenAI(t *testing.T, wantAuth string, wantStream bool, handle func(w http.ResponseWriter)) *OpenAIClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("request = %s %s, want POST /v1/chat/completions", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != wantAuth {
+			t.Errorf("Authorization = %q, want %q", got, wantAuth)
+		}
+
+		var body chatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		if body.Model != "test-model" || body.Stream != wantStream {
+			t.Errorf("model = %q, stream = %v, want test-model, %v", body.Model, body.Stream, wantStream)
+		}
+		if len(body.Messages) != 2 || body.Messages[0].Role != "system" || body.Messages[0].Content != systemPrompt ||
+			body.Messages[1].Role != "user" || !strings.Contains(body.Messages[1].Content, "Alice: hello") {
+			t.Errorf("messages = %+v, want the system prompt then the transcript", body.Messages)
+		}
+		handle(w)
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv("OPENAI_API_URL", server.URL+"/v1/")
+	t.Setenv("OPENAI_MODEL", "test-model")
+	t.Setenv("OPENAI_API_KEY", strings.TrimPrefix(wantAuth, "Bearer "))
+	client, err := NewOpenAIClient()
+	if err != nil {
+		t.Fatalf("NewOpenAIClient: %v", err)
+	}
+	return client
+}
+
+var testRequest = Request{Text: "Alice: hello", Lang: "en"}
+
+func TestOpenAISummarize(t *testing.T) {
+	for _, auth := range []string{"Bearer sk-test", ""} {
+		client := fakeOpenAI(t, auth, false, func(w http.ResponseWriter) {
+			fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"  Alice said hello.\n"}}]}`)
+		})
+
+		summary, err := client.Summarize(context.Background(), testRequest)
+		if err != nil {
+			t.Fatalf("Summarize: %v", err)
+		}
+		if summary != "Alice said hello." {
+			t.Errorf("summary = %q, want %q", summary, "Alice said hello.")
+		}
+	}
+}
+
+func TestOpenAISummarizeErrors(t *testing.T) {
+	for name, handle := range map[string]func(w http.ResponseWriter){
+		"no choices": func(w http.ResponseWriter) {
+			fmt.Fprint(w, `{"choices":[]}`)
+		},
+		"invalid JSON": func(w http.ResponseWriter) {
+			fmt.Fprint(w, `<html>`)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client := fakeOpenAI(t, "", false, handle)
+			if _, err := client.Summarize(context.Background(), testRequest); err == nil {
+				t.Fatal("Summarize succeeded, want an error")
+			}
+		})
+	}
+
+	client := fakeOpenAI(t, "", false, func(w http.ResponseWriter) {
+		http.Error(w, "model overloaded", http.StatusServiceUnavailable)
+	})
+	_, err := client.Summarize(context.Background(), testRequest)
+	if err == nil || !strings.Contains(err.Error(), "503") || !strings.Contains(err.Error(), "model overloaded") {
+		t.Errorf("error = %v, want the status and body", err)
+	}
+}
+
+func TestOpenAISummarizeStream(t *testing.T) {
+	client := fakeOpenAI(t, "Bearer sk-test", true, func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": keep-alive\n\n")
+		fmt.Fprint(w, `data: {"choices":[{"delta":{"role":"assistant"}}]}`+"\n\n")
+		fmt.Fprint(w, `data: {"choices":[{"delta":{"content":"Alice "}}]}`+"\n\n")
+		fmt.Fprint(w, `data: {"choices":[{"delta":{"content":"said hello."}}]}`+"\n\n")
+		fmt.Fprint(w, `data: {"choices":[]}`+"\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+
+	var progress []string
+	summary, err := client.SummarizeStream(context.Background(), testRequest, func(text string) {
+		progress = append(progress, text)
+	})
+	if err != nil {
+		t.Fatalf("SummarizeStream: %v", err)
+	}
+	if summary != "Alice said hello." {
+		t.Errorf("summary = %q, want %q", summary, "Alice said hello.")
+	}
+	if want := []string{"Alice ", "Alice said hello."}; !reflect.DeepEqual(progress, want) {
+		t.Errorf("progress = %q, want %q", progress, want)
+	}
+}
+
+func TestOpenAISummarizeStreamErrors(t *testing.T) {
+	for name, body := range map[string]string{
+		"error event": `data: {"choices":[{"delta":{"content":"Alice"}}]}` + "\n\n" + `data: {"error":{"message":"context length exceeded"}}` + "\n\n",
+		"truncated":   `data: {"choices":[{"delta":{"content":"Alice"}}]}` + "\n\n",
+		"invalid":     "data: {\n\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			client := fakeOpenAI(t, "", true, func(w http.ResponseWriter) {
+				fmt.Fprint(w, body)
+			})
+			if _, err := client.SummarizeStream(context.Background(), testRequest, nil); err == nil {
+				t.Fatal("SummarizeStream succeeded, want an error")
+			}
+		})
+	}
+}
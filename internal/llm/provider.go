@@ -0,0 +1,95 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Task selects the kind of prompt sent for a Request.
+type Task int
+
+const (
+	// TaskSummarize summarizes a chat transcript.
+	TaskSummarize Task = iota
+	// TaskCombine merges partial summaries of consecutive transcript chunks.
+	TaskCombine
+)
+
+// Request holds the input for a single summarization call.
+type Request struct {
+	Text string
+	Lang string
+	Task Task
+	// Style is one of Styles; empty keeps the plain prompt.
+	Style string
+}
+
+// prompt renders the request as the prompt sent to the model.
+func (r Request) prompt() string {
+	if r.Task == TaskCombine {
+		return withStyle(constructCombinePrompt(r.Text, r.Lang), r.Style, r.Lang)
+	}
+	return withStyle(constructPrompt(r.Text, r.Lang), r.Style, r.Lang)
+}
+
+// Summarizer is implemented by every LLM backend able to summarize a chat transcript.
+type Summarizer interface {
+	Summarize(ctx context.Context, req Request) (string, error)
+}
+
+// StreamSummarizer is implemented by backends able to stream the summary while it is generated.
+// onProgress receives the text accumulated so far and may be nil.
+type StreamSummarizer interface {
+	Summarizer
+	SummarizeStream(ctx context.Context, req Request, onProgress func(text string)) (string, error)
+}
+
+// Factory builds a Summarizer from the environment.
+type Factory func() (Summarizer, error)
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]Factory{}
+)
+
+// Register makes a provider available under the given name.
+// It panics if the name is registered twice, like database/sql drivers do.
+func Register(name string, factory Factory) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	if factory == nil {
+		panic("llm: Register factory is nil")
+	}
+	if _, dup := providers[name]; dup {
+		panic("llm: Register called twice for provider " + name)
+	}
+	providers[name] = factory
+}
+
+// New returns the Summarizer registered under name.
+func New(name string) (Summarizer, error) {
+	providersMu.RLock()
+	factory, ok := providers[name]
+	providersMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown LLM provider %q (available: %v)", name, Providers())
+	}
+	return factory()
+}
+
+// Providers returns the sorted names of the registered providers.
+func Providers() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
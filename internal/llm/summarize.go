@@ -1,53 +1,10 @@
 package llm
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
-	"net/http"
-	"os"
+	"strings"
 )
 
-// Summarize sends a request to the Ollama LLM server and waits until done is true.
-func Summarize(text string, lang string) (string, error) {
-	ollamaAPIURL := os.Getenv("OLLAMA_API_URL")
-	model := os.Getenv("OLLAMA_MODEL")
-	if model == "" {
-		return "", fmt.Errorf("OLLAMA_MODEL environment variable is not set")
-	}
-
-	prompt := constructPrompt(text, lang)
-
-	requestBody, err := json.Marshal(map[string]interface{}{
-		"model":  model,
-		"prompt": prompt,
-		"stream": false,
-	})
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request body: %v", err)
-	}
-
-	resp, err := http.Post(ollamaAPIURL, "application/json", bytes.NewBuffer(requestBody))
-	if err != nil {
-		return "", fmt.Errorf("failed to send request to Ollama API: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("received non-200 response from Ollama API: %s", resp.Status)
-	}
-
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode response: %v", err)
-	}
-	summary, ok := result["response"].(string)
-	if !ok {
-		return "", fmt.Errorf("response field not found in the API response")
-	}
-	return summary, nil
-}
-
 // constructPrompt creates a prompt for the LLM based on the specified language.
 func constructPrompt(text string, lang string) string {
 	switch lang {
@@ -61,3 +18,59 @@ func constructPrompt(text string, lang string) string {
 		return text // fallback para texto puro
 	}
 }
+
+// constructCombinePrompt asks the LLM to merge partial summaries into a single one.
+func constructCombinePrompt(text string, lang string) string {
+	switch lang {
+	case "pt":
+		return fmt.Sprintf("Os textos a seguir são resumos de partes consecutivas de uma mesma conversa do Telegram. Combine-os em um único resumo coerente em Português:\n%s", text)
+	case "en":
+		return fmt.Sprintf("The following texts are summaries of consecutive parts of the same Telegram chat. Combine them into a single coherent summary in English:\n%s", text)
+	case "es":
+		return fmt.Sprintf("Los siguientes textos son resúmenes de partes consecutivas de un mismo chat de Telegram. Combínalos en un único resumen coherente en español:\n%s", text)
+	default:
+		return text
+	}
+}
+
+// Styles lists the supported summary styles.
+var Styles = []string{"brief", "bullets", "detailed"}
+
+// IsValidStyle reports whether style is one of Styles.
+func IsValidStyle(style string) bool {
+	for _, s := range Styles {
+		if s == style {
+			return true
+		}
+	}
+	return false
+}
+
+var styleInstructions = map[string]map[string]string{
+	"pt": {
+		"brief":    "Seja breve: no máximo três frases.",
+		"bullets":  "Responda em tópicos curtos, um por assunto discutido.",
+		"detailed": "Seja detalhado: cubra cada assunto, as decisões tomadas e quem participou.",
+	},
+	"en": {
+		"brief":    "Be brief: three sentences at most.",
+		"bullets":  "Answer with short bullet points, one per topic discussed.",
+		"detailed": "Be detailed: cover every topic, the decisions made and who took part.",
+	},
+	"es": {
+		"brief":    "Sé breve: tres frases como máximo.",
+		"bullets":  "Responde con viñetas cortas, una por tema tratado.",
+		"detailed": "Sé detallado: cubre cada tema, las decisiones tomadas y quién participó.",
+	},
+}
+
+// withStyle places the style instruction right after the first line of the prompt,
+// before the transcript.
+func withStyle(prompt, style, lang string) string {
+	instruction, ok := styleInstructions[lang][style]
+	if !ok {
+		return prompt
+	}
+	head, transcript, _ := strings.Cut(prompt, "\n")
+	return head + " " + instruction + "\n" + transcript
+}
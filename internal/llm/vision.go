@@ -0,0 +1,128 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/option"
+)
+
+// ImageDescriber is implemented by multimodal providers that can describe pictures.
+type ImageDescriber interface {
+	// DescribeImage returns a short description of image, written in lang.
+	DescribeImage(ctx context.Context, image []byte, mimeType, lang string) (string, error)
+}
+
+// NewImageDescriber returns the first provider of names able to describe images, and its name.
+func NewImageDescriber(names []string) (ImageDescriber, string, error) {
+	for _, spec := range names {
+		entry, err := ParseChainEntry(spec)
+		if err != nil {
+			return nil, "", err
+		}
+		provider, err := New(entry.Name)
+		if err != nil {
+			return nil, "", err
+		}
+		if describer, ok := provider.(ImageDescriber); ok {
+			return describer, entry.Name, nil
+		}
+	}
+	return nil, "", fmt.Errorf("none of the providers %v can describe images", names)
+}
+
+// imagePrompt asks for a description short enough to fit in a transcript line.
+func imagePrompt(lang string) string {
+	switch lang {
+	case "pt":
+		return "Descreva esta imagem em uma frase curta, em Português. Transcreva qualquer texto importante que apareça nela."
+	case "es":
+		return "Describe esta imagen en una frase corta, en español. Transcribe cualquier texto importante que aparezca en ella."
+	default:
+		return "Describe this image in one short sentence, in English. Transcribe any important text shown in it."
+	}
+}
+
+// DescribeImage sends the image to Gemini, whose models are all multimodal.
+func (c *GeminiClient) DescribeImage(ctx context.Context, image []byte, mimeType, lang string) (string, error) {
+	client, err := genai.NewClient(ctx, option.WithAPIKey(c.APIkey))
+	if err != nil {
+		return "", fmt.Errorf("failed to create Gemini client: %v", err)
+	}
+	defer client.Close()
+
+	model := client.GenerativeModel(c.ModelName)
+	model.SetTemperature(0.2)
+	model.SetMaxOutputTokens(128)
+
+	format := strings.TrimPrefix(mimeType, "image/")
+	resp, err := model.GenerateContent(ctx, genai.ImageData(format, image), genai.Text(imagePrompt(lang)))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate content: %v", err)
+	}
+
+	var description strings.Builder
+	if len(resp.Candidates) > 0 && resp.Candidates[0].Content != nil {
+		for _, part := range resp.Candidates[0].Content.Parts {
+			if text, ok := part.(genai.Text); ok {
+				description.WriteString(string(text))
+			}
+		}
+	}
+	if description.Len() == 0 {
+		return "", fmt.Errorf("no response generated")
+	}
+	return strings.TrimSpace(description.String()), nil
+}
+
+// DescribeImage sends the image to the vision model of Ollama (a llava-style model).
+func (c *OllamaClient) DescribeImage(ctx context.Context, image []byte, mimeType, lang string) (string, error) {
+	model := c.VisionModelName
+	if model == "" {
+		model = c.ModelName
+	}
+
+	requestBody, err := json.Marshal(map[string]interface{}{
+		"model":  model,
+		"prompt": imagePrompt(lang),
+		"images": []string{base64.StdEncoding.EncodeToString(image)},
+		"stream": false,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request body: %v", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTPClient.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request to Ollama API: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("received non-200 response from Ollama API: %s", resp.Status)
+	}
+
+	var result struct {
+		Response string `json:"response"`
+		Error    string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode response: %v", err)
+	}
+	if result.Error != "" {
+		return "", fmt.Errorf("ollama error: %s", result.Error)
+	}
+	return strings.TrimSpace(result.Response), nil
+}
@@ -0,0 +1,108 @@
+package llm
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeVision describes every image the same way; fakeSummarizer cannot describe any.
+type fakeVision struct{ fakeSummarizer }
+
+func (fakeVision) DescribeImage(ctx context.Context, image []byte, mimeType, lang string) (string, error) {
+	return "a cat", nil
+}
+
+func init() {
+	Register("fake-text", func() (Summarizer, error) { return &fakeSummarizer{}, nil })
+	Register("fake-vision", func() (Summarizer, error) { return &fakeVision{}, nil })
+}
+
+func TestNewImageDescriber(t *testing.T) {
+	describer, name, err := NewImageDescriber([]string{"fake-text", "fake-vision:5s"})
+	if err != nil {
+		t.Fatalf("NewImageDescriber: %v", err)
+	}
+	if _, ok := describer.(*fakeVision); !ok || name != "fake-vision" {
+		t.Errorf("describer = %T named %q, want the first multimodal provider", describer, name)
+	}
+
+	if _, _, err := NewImageDescriber([]string{"fake-text"}); err == nil {
+		t.Error("NewImageDescriber succeeded without a multimodal provider")
+	}
+}
+
+// fakeOllamaVision serves the generate endpoint with body, after checking that the
+// image was sent to wantModel.
+func fakeOllamaVision(t *testing.T, wantModel string, status int, body string) *OllamaClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model  string   `json:"model"`
+			Prompt string   `json:"prompt"`
+			Images []string `json:"images"`
+			Stream bool     `json:"stream"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		if req.Model != wantModel || req.Stream || req.Prompt != imagePrompt("pt") {
+			t.Errorf("request = %+v, want an unstreamed Portuguese prompt for %s", req, wantModel)
+		}
+		if len(req.Images) != 1 || req.Images[0] != base64.StdEncoding.EncodeToString([]byte("PNG")) {
+			t.Errorf("images = %q, want the encoded image", req.Images)
+		}
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+
+	return &OllamaClient{BaseURL: server.URL + "/api/generate", HTTPClient: server.Client(), ModelName: "test-model"}
+}
+
+func TestOllamaDescribeImage(t *testing.T) {
+	for name, tc := range map[string]struct {
+		visionModel string
+		wantModel   string
+	}{
+		"vision model":                 {visionModel: "llava", wantModel: "llava"},
+		"falls back to the text model": {wantModel: "test-model"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client := fakeOllamaVision(t, tc.wantModel, http.StatusOK, `{"response":" Um gato.\n"}`)
+			client.VisionModelName = tc.visionModel
+
+			description, err := client.DescribeImage(context.Background(), []byte("PNG"), "image/png", "pt")
+			if err != nil {
+				t.Fatalf("DescribeImage: %v", err)
+			}
+			if description != "Um gato." {
+				t.Errorf("description = %q, want the trimmed response", description)
+			}
+		})
+	}
+}
+
+func TestOllamaDescribeImageErrors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		status  int
+		body    string
+		wantErr string
+	}{
+		"status":      {http.StatusNotFound, `{"error":"model not found"}`, "404"},
+		"error field": {http.StatusOK, `{"error":"model does not support images"}`, "does not support images"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client := fakeOllamaVision(t, "test-model", tc.status, tc.body)
+			_, err := client.DescribeImage(context.Background(), []byte("PNG"), "image/png", "pt")
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("DescribeImage error = %v, want one mentioning %q", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package redact
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Pseudonyms replaces the names of chat members with stable aliases ("User1",
+// "User2", ...) and maps the aliases back once the summary is written.
+type Pseudonyms struct {
+	// aliases maps every known spelling of a member's name to their alias.
+	aliases map[string]string
+	// names maps each alias back to the member's display name.
+	names map[string]string
+}
+
+// NewPseudonyms returns an empty set of pseudonyms.
+func NewPseudonyms() *Pseudonyms {
+	return &Pseudonyms{aliases: map[string]string{}, names: map[string]string{}}
+}
+
+// Add registers a member by display name and any other spellings (first name,
+// username, ...) and returns their alias. Members already known keep their alias.
+func (p *Pseudonyms) Add(displayName string, otherNames ...string) string {
+	alias, ok := p.aliases[displayName]
+	if !ok {
+		alias = fmt.Sprintf("User%d", len(p.names)+1)
+		p.names[alias] = displayName
+	}
+	for _, name := range append([]string{displayName}, otherNames...) {
+		if name = strings.TrimSpace(name); name != "" {
+			if _, taken := p.aliases[name]; !taken {
+				p.aliases[name] = alias
+			}
+		}
+	}
+	return alias
+}
+
+// Apply replaces every known name in text with its alias.
+func (p *Pseudonyms) Apply(text string) string {
+	return replaceWords(text, p.aliases)
+}
+
+// Restore replaces the aliases in text with the display names they stand for.
+func (p *Pseudonyms) Restore(text string) string {
+	return replaceWords(text, p.names)
+}
+
+// replaceWords replaces whole-word occurrences of the keys of replacements, longest first
+// so that "Ana Maria" wins over "Ana" and "User12" over "User1".
+func replaceWords(text string, replacements map[string]string) string {
+	keys := make([]string, 0, len(replacements))
+	for key := range replacements {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+
+	var sb strings.Builder
+	for i := 0; i < len(text); {
+		matched := false
+		if i == 0 || !isWordRune(lastRune(text[:i])) {
+			for _, key := range keys {
+				end := i + len(key)
+				if strings.HasPrefix(text[i:], key) && (end == len(text) || !isWordRune(firstRune(text[end:]))) {
+					sb.WriteString(replacements[key])
+					i = end
+					matched = true
+					break
+				}
+			}
+		}
+		if !matched {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			sb.WriteString(text[i : i+size])
+			i += size
+		}
+	}
+	return sb.String()
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+func firstRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
+
+func lastRune(s string) rune {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
@@ -0,0 +1,168 @@
+// Package redact removes personal data from chat transcripts before they are sent
+// to a summarization provider.
+package redact
+
+import (
+	"math/big"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Redaction modes, from least to most thorough.
+const (
+	// Off sends the transcript unchanged.
+	Off = "off"
+	// PII masks phone numbers, emails, card numbers, IBANs and URL credentials.
+	PII = "pii"
+	// All also replaces sender names with pseudonyms, mapped back in the summary.
+	All = "all"
+)
+
+// Modes lists the accepted redaction modes.
+var Modes = []string{Off, PII, All}
+
+// IsValidMode reports whether mode is one of Modes.
+func IsValidMode(mode string) bool {
+	for _, m := range Modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	urlPattern   = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"]+`)
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	ibanPattern  = regexp.MustCompile(`\b[A-Z]{2}[0-9]{2}(?: ?[A-Z0-9]){11,30}\b`)
+	cardPattern  = regexp.MustCompile(`\b[0-9](?:[ -]?[0-9]){12,18}\b`)
+	// phonePattern matches numbers with a country code, or national numbers written
+	// in at least three groups, so that plain runs of digits such as order numbers
+	// are left alone.
+	phonePattern = regexp.MustCompile(`\+[0-9](?:[ ().-]{0,2}[0-9]){6,14}|(?:\([0-9]{2,4}\)|\b[0-9]{2,4})(?:[ -][0-9]{2,4}){2,4}\b`)
+	// datePattern matches the dates that group like national phone numbers.
+	datePattern = regexp.MustCompile(`[0-9]{4}-[0-9]{2}-[0-9]{2}|[0-9]{2}-[0-9]{2}-[0-9]{4}`)
+)
+
+// sensitiveParams are query parameters whose values are credentials.
+var sensitiveParams = []string{"token", "key", "secret", "password", "pass", "pwd", "auth", "sig", "signature", "session", "code", "credential"}
+
+// PIIText masks personal data in text: URL credentials, emails, IBANs, card numbers
+// and phone numbers, in that order so that broader patterns do not eat narrower ones.
+func PIIText(text string) string {
+	text = urlPattern.ReplaceAllStringFunc(text, redactURL)
+	text = emailPattern.ReplaceAllString(text, "[email]")
+	text = ibanPattern.ReplaceAllStringFunc(text, func(match string) string {
+		if validIBAN(match) {
+			return "[iban]"
+		}
+		return match
+	})
+	text = cardPattern.ReplaceAllStringFunc(text, func(match string) string {
+		if validLuhn(match) {
+			return "[card]"
+		}
+		return match
+	})
+	text = phonePattern.ReplaceAllStringFunc(text, func(match string) string {
+		digits := countDigits(match)
+		// Without a country code, require enough digits to tell phones from amounts.
+		if digits > 15 || digits < 8 || (digits < 9 && !strings.HasPrefix(match, "+")) {
+			return match
+		}
+		if datePattern.MatchString(match) {
+			return match
+		}
+		return "[phone]"
+	})
+	return text
+}
+
+// redactURL drops user info and the query string of URLs that carry credentials.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "[url]"
+	}
+	if !hasCredentials(u) {
+		return raw
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String() + "?[redacted]"
+}
+
+// HasCredentials reports whether a URL carries user info or a query parameter named
+// like a credential, such as a token, key or session. URLs that cannot be parsed are
+// assumed to carry some.
+func HasCredentials(raw string) bool {
+	u, err := url.Parse(raw)
+	return err != nil || hasCredentials(u)
+}
+
+func hasCredentials(u *url.URL) bool {
+	if u.User != nil {
+		return true
+	}
+	for param := range u.Query() {
+		param = strings.ToLower(param)
+		for _, name := range sensitiveParams {
+			if strings.Contains(param, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validIBAN checks the ISO 13616 mod-97 checksum.
+func validIBAN(iban string) bool {
+	iban = strings.ReplaceAll(iban, " ", "")
+	rearranged := iban[4:] + iban[:4]
+	var digits strings.Builder
+	for _, r := range rearranged {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			digits.WriteString(big.NewInt(int64(r - 'A' + 10)).String())
+		default:
+			return false
+		}
+	}
+	n, ok := new(big.Int).SetString(digits.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// validLuhn checks the checksum used by payment card numbers.
+func validLuhn(number string) bool {
+	sum, double := 0, false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func countDigits(s string) int {
+	n := 0
+	for _, r := range s {
+		if unicode.IsDigit(r) {
+			n++
+		}
+	}
+	return n
+}
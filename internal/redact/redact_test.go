@@ -0,0 +1,60 @@
+package redact
+
+import "testing"
+
+func TestPIIText(t *testing.T) {
+	for _, tc := range []struct {
+		name, in, want string
+	}{
+		{"email", "write to jane.doe+tldr@example.co.uk today", "write to [email] today"},
+		{"international phone", "call +44 20 7946 0958 now", "call [phone] now"},
+		{"international phone with area code", "call +1 (555) 123-4567", "call [phone]"},
+		{"international phone without separators", "call +493012345678", "call [phone]"},
+		{"national phone in groups", "call 555-123-4567", "call [phone]"},
+		{"national phone with parenthesized area code", "call (555) 123-4567", "call [phone]"},
+		{"national phone in pairs", "appelle 06 12 34 56 78", "appelle [phone]"},
+		{"card passing Luhn", "card 4111 1111 1111 1111 please", "card [card] please"},
+		{"card failing Luhn", "card 4111 1111 1111 1112 please", "card 4111 1111 1111 1112 please"},
+		{"valid IBAN", "IBAN GB82 WEST 1234 5698 7654 32 thanks", "IBAN [iban] thanks"},
+		{"URL credentials", "see https://example.com/reset?token=abc123", "see https://example.com/reset?[redacted]"},
+		{"plain URL", "see https://example.com/docs?page=2", "see https://example.com/docs?page=2"},
+
+		{"ISO date and time", "meet on 2024-10-17 14:30 at the office", "meet on 2024-10-17 14:30 at the office"},
+		{"day-first date and time", "meet on 17-10-2024 14:30", "meet on 17-10-2024 14:30"},
+		{"time range", "open 09:00-17:30 every day", "open 09:00-17:30 every day"},
+		{"order number", "order 123456789012 shipped", "order 123456789012 shipped"},
+		{"prefixed order number", "ticket ORD-2024-001234 closed", "ticket ORD-2024-001234 closed"},
+		{"version string", "upgrade to 10.0.19045.2965", "upgrade to 10.0.19045.2965"},
+		{"IP address", "ping 192.168.100.200", "ping 192.168.100.200"},
+		{"amount", "it cost 12.345.678 in total", "it cost 12.345.678 in total"},
+		{"short number", "room 1234-56", "room 1234-56"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := PIIText(tc.in); got != tc.want {
+				t.Errorf("PIIText(%q) = %q, want %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPseudonyms(t *testing.T) {
+	p := NewPseudonyms()
+	ana := p.Add("Ana Maria", "Ana", "anam")
+	bob := p.Add("Bob")
+	if again := p.Add("Ana Maria", "Ana"); again != ana {
+		t.Errorf("Add of a known member = %q, want %q", again, ana)
+	}
+	if ana == bob {
+		t.Fatalf("two members share alias %q", ana)
+	}
+
+	in := "Ana Maria asked Bob; Ana and @anam agreed. Banana stays."
+	applied := p.Apply(in)
+	want := ana + " asked " + bob + "; " + ana + " and @" + ana + " agreed. Banana stays."
+	if applied != want {
+		t.Errorf("Apply = %q, want %q", applied, want)
+	}
+	if restored := p.Restore(ana + " thanked " + bob); restored != "Ana Maria thanked Bob" {
+		t.Errorf("Restore = %q", restored)
+	}
+}
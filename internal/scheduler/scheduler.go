@@ -0,0 +1,123 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"tldr-telegram-bot/internal/db"
+)
+
+// checkInterval is how often the scheduler looks for due digests.
+const checkInterval = time.Minute
+
+// DigestFunc produces and posts the digest of a group covering [since, until). It
+// returns an error if the digest could not be posted.
+type DigestFunc func(groupID int64, since, until time.Time) error
+
+// Scheduler periodically runs the digests stored in the digest_schedules table.
+type Scheduler struct {
+	store  db.MessageStore
+	digest DigestFunc
+}
+
+// New creates a scheduler that calls digest for every due schedule.
+func New(store db.MessageStore, digest DigestFunc) *Scheduler {
+	return &Scheduler{store: store, digest: digest}
+}
+
+// Run checks for due digests every minute until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	s.runDue(ctx, time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.runDue(ctx, now)
+		}
+	}
+}
+
+func (s *Scheduler) runDue(ctx context.Context, now time.Time) {
+	schedules, err := s.store.GetDueDigestSchedules(ctx, now)
+	if err != nil {
+		log.Printf("Error loading due digests: %v", err)
+		return
+	}
+
+	for _, schedule := range schedules {
+		if ctx.Err() != nil {
+			return
+		}
+		next, err := NextRun(schedule, now)
+		if err != nil {
+			log.Printf("Error scheduling digest for group %d: %v", schedule.GroupID, err)
+			continue
+		}
+
+		since := schedule.LastRun
+		if since.IsZero() {
+			since = now.Add(-period(schedule.Frequency))
+		}
+
+		// Advance the schedule before running so a failing digest is not retried every
+		// minute. The last run only moves once the digest is posted, so the next one
+		// covers the messages of a failed one too.
+		if err := s.store.AdvanceDigestSchedule(ctx, schedule.GroupID, next); err != nil {
+			log.Printf("Error updating digest schedule for group %d: %v", schedule.GroupID, err)
+			continue
+		}
+
+		log.Printf("Running %s digest for group %d", schedule.Frequency, schedule.GroupID)
+		if err := s.digest(schedule.GroupID, since, now); err != nil {
+			log.Printf("Error running digest for group %d: %v", schedule.GroupID, err)
+			continue
+		}
+		if err := s.store.MarkDigestRun(ctx, schedule.GroupID, now); err != nil {
+			log.Printf("Error updating digest schedule for group %d: %v", schedule.GroupID, err)
+		}
+	}
+}
+
+// NextRun returns the first time strictly after the given instant at which the schedule fires.
+func NextRun(schedule db.DigestSchedule, after time.Time) (time.Time, error) {
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timezone %q: %w", schedule.Timezone, err)
+	}
+
+	local := after.In(loc)
+	year, month, day := local.Date()
+	// Days are counted on the calendar rather than added to a time, which a time
+	// skipped by DST would have moved an hour.
+	at := func(days int) time.Time {
+		return time.Date(year, month, day+days, schedule.Hour, schedule.Minute, 0, 0, loc)
+	}
+
+	var days, step int
+	switch schedule.Frequency {
+	case db.DigestDaily:
+		step = 1
+	case db.DigestWeekly:
+		days, step = (int(schedule.Weekday)-int(local.Weekday())+7)%7, 7
+	default:
+		return time.Time{}, fmt.Errorf("unknown digest frequency %q", schedule.Frequency)
+	}
+	if !at(days).After(after) {
+		days += step
+	}
+	return at(days), nil
+}
+
+// period is how far back the first digest of a schedule reaches.
+func period(frequency string) time.Duration {
+	if frequency == db.DigestWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
@@ -0,0 +1,168 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tldr-telegram-bot/internal/db"
+)
+
+func TestNextRun(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+	at := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, berlin)
+	}
+	daily := func(hour, minute int) db.DigestSchedule {
+		return db.DigestSchedule{Frequency: db.DigestDaily, Hour: hour, Minute: minute, Timezone: "Europe/Berlin"}
+	}
+	weekly := func(weekday time.Weekday, hour, minute int) db.DigestSchedule {
+		return db.DigestSchedule{Frequency: db.DigestWeekly, Weekday: weekday, Hour: hour, Minute: minute, Timezone: "Europe/Berlin"}
+	}
+
+	for name, tc := range map[string]struct {
+		schedule db.DigestSchedule
+		after    time.Time
+		want     time.Time
+	}{
+		"daily, later today":        {daily(18, 0), at(2026, 6, 10, 9, 0), at(2026, 6, 10, 18, 0)},
+		"daily, already past today": {daily(8, 0), at(2026, 6, 10, 9, 0), at(2026, 6, 11, 8, 0)},
+		"daily, right at the time":  {daily(9, 0), at(2026, 6, 10, 9, 0), at(2026, 6, 11, 9, 0)},
+		"daily, across the year":    {daily(8, 0), at(2026, 12, 31, 9, 0), at(2027, 1, 1, 8, 0)},
+		// Wednesday the 10th.
+		"weekly, later this week":       {weekly(time.Friday, 17, 0), at(2026, 6, 10, 9, 0), at(2026, 6, 12, 17, 0)},
+		"weekly, wraps to next week":    {weekly(time.Monday, 9, 0), at(2026, 6, 10, 9, 0), at(2026, 6, 15, 9, 0)},
+		"weekly, same day, later":       {weekly(time.Wednesday, 18, 0), at(2026, 6, 10, 9, 0), at(2026, 6, 10, 18, 0)},
+		"weekly, same day, past":        {weekly(time.Wednesday, 8, 0), at(2026, 6, 10, 9, 0), at(2026, 6, 17, 8, 0)},
+		"weekly, across the month":      {weekly(time.Tuesday, 9, 0), at(2026, 6, 30, 10, 0), at(2026, 7, 7, 9, 0)},
+		"daily, spring forward":         {daily(9, 0), at(2026, 3, 28, 9, 0), at(2026, 3, 29, 9, 0)},
+		"daily, fall back":              {daily(9, 0), at(2026, 10, 24, 9, 0), at(2026, 10, 25, 9, 0)},
+		"weekly, across spring":         {weekly(time.Saturday, 9, 0), at(2026, 3, 28, 9, 0), at(2026, 4, 4, 9, 0)},
+		"daily, in the skipped hour":    {daily(2, 30), at(2026, 3, 28, 9, 0), at(2026, 3, 29, 3, 30)},
+		"daily, after the skipped hour": {daily(2, 30), at(2026, 3, 29, 3, 30), at(2026, 3, 30, 2, 30)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := NextRun(tc.schedule, tc.after)
+			if err != nil {
+				t.Fatalf("NextRun: %v", err)
+			}
+			if !got.Equal(tc.want) {
+				t.Errorf("NextRun = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNextRunKeepsWallClockAcrossDST(t *testing.T) {
+	// The day clocks go forward is 23 hours long, the day they go back 25.
+	for after, wantGap := range map[string]time.Duration{
+		"2026-03-28T09:00:00+01:00": 23 * time.Hour,
+		"2026-10-24T09:00:00+02:00": 25 * time.Hour,
+	} {
+		start, err := time.Parse(time.RFC3339, after)
+		if err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		schedule := db.DigestSchedule{Frequency: db.DigestDaily, Hour: 9, Timezone: "Europe/Berlin"}
+		next, err := NextRun(schedule, start)
+		if err != nil {
+			t.Fatalf("NextRun: %v", err)
+		}
+		if gap := next.Sub(start); gap != wantGap {
+			t.Errorf("after %s: next run in %s, want %s", after, gap, wantGap)
+		}
+	}
+}
+
+func TestNextRunFiresOnceOnFallBack(t *testing.T) {
+	// 02:30 happens twice on the night clocks go back; the digest runs only once.
+	schedule := db.DigestSchedule{Frequency: db.DigestDaily, Hour: 2, Minute: 30, Timezone: "Europe/Berlin"}
+	first, err := NextRun(schedule, time.Date(2026, 10, 24, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("NextRun: %v", err)
+	}
+	second, err := NextRun(schedule, first)
+	if err != nil {
+		t.Fatalf("NextRun: %v", err)
+	}
+	if gap := second.Sub(first); gap != 24*time.Hour {
+		t.Errorf("runs at %s and %s, want them a day apart", first, second)
+	}
+}
+
+func TestNextRunRejectsBadSchedules(t *testing.T) {
+	for name, schedule := range map[string]db.DigestSchedule{
+		"unknown timezone":  {Frequency: db.DigestDaily, Timezone: "Mars/Olympus"},
+		"unknown frequency": {Frequency: "hourly", Timezone: "UTC"},
+	} {
+		if _, err := NextRun(schedule, time.Now()); err == nil {
+			t.Errorf("%s: NextRun succeeded", name)
+		}
+	}
+}
+
+func TestRunDueRetriesRangeOfFailedDigests(t *testing.T) {
+	ctx := context.Background()
+	store, err := db.Open("sqlite://"+filepath.Join(t.TempDir(), "bot.db"), nil)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if _, err := store.MigrateUp(ctx); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+
+	day := func(n int) time.Time { return time.Date(2026, 6, n, 9, 0, 0, 0, time.UTC) }
+	const groupID = -100
+	err = store.SaveDigestSchedule(ctx, db.DigestSchedule{GroupID: groupID, Frequency: db.DigestDaily, Hour: 9, Timezone: "UTC", NextRun: day(10)})
+	if err != nil {
+		t.Fatalf("SaveDigestSchedule: %v", err)
+	}
+
+	var ranges [][2]time.Time
+	fail := false
+	s := New(store, func(group int64, since, until time.Time) error {
+		ranges = append(ranges, [2]time.Time{since, until})
+		if fail {
+			return errors.New("telegram is down")
+		}
+		return nil
+	})
+
+	for _, run := range []struct {
+		now  time.Time
+		fail bool
+	}{
+		{day(10), false},
+		{day(11), true},
+		{day(12), false},
+	} {
+		fail = run.fail
+		s.runDue(ctx, run.now)
+
+		schedule, err := store.GetDigestSchedule(ctx, groupID)
+		if err != nil {
+			t.Fatalf("GetDigestSchedule: %v", err)
+		}
+		// The schedule moves on either way, so a failing digest is not retried every minute.
+		if want := run.now.AddDate(0, 0, 1); !schedule.NextRun.Equal(want) {
+			t.Errorf("after %s: next run = %s, want %s", run.now, schedule.NextRun, want)
+		}
+	}
+
+	// The digest after the failed one covers its range too.
+	want := [][2]time.Time{{day(9), day(10)}, {day(10), day(11)}, {day(10), day(12)}}
+	if len(ranges) != len(want) {
+		t.Fatalf("ran %d digests, want %d", len(ranges), len(want))
+	}
+	for i := range want {
+		if !ranges[i][0].Equal(want[i][0]) || !ranges[i][1].Equal(want[i][1]) {
+			t.Errorf("digest %d covers %s to %s, want %s to %s", i, ranges[i][0], ranges[i][1], want[i][0], want[i][1])
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"golang.org/x/time/rate"
+)
+
+// Telegram's documented limits: about 30 messages per second overall, one per
+// second in a private chat and 20 per minute in a group.
+const (
+	globalRate  = 30
+	privateRate = rate.Limit(1)
+	groupRate   = rate.Limit(20.0 / 60)
+)
+
+const (
+	// maxAttempts bounds how often one request is tried.
+	maxAttempts = 5
+	// firstBackoff is the wait before retrying a server error; it doubles on every attempt.
+	firstBackoff = time.Second
+	// maxRetryAfter caps the wait Telegram may ask for after a 429.
+	maxRetryAfter = 5 * time.Minute
+)
+
+// metrics exposes the sender under "sender" in /debug/vars:
+//
+//	sent          requests delivered
+//	failed        requests given up on
+//	rate_limited  429 responses received
+//	retried       requests retried after a 429 or a server error
+//	dropped       best-effort requests dropped because the chat was busy
+var metrics = expvar.NewMap("sender")
+
+// ErrBusy is returned by TrySend when the request would have to wait its turn.
+var ErrBusy = errors.New("chat is busy")
+
+// Sender posts to Telegram within its rate limits. Requests to a chat are delivered
+// one at a time, in the order they were made, and retried when Telegram answers
+// with 429 Too Many Requests or a server error.
+type Sender struct {
+	api    *tgbotapi.BotAPI
+	global *rate.Limiter
+
+	mu    sync.Mutex
+	chats map[int64]*chatQueue
+}
+
+// chatQueue orders the requests to one chat.
+type chatQueue struct {
+	limiter *rate.Limiter
+	// last is closed once the most recent request to the chat is done.
+	last    chan struct{}
+	pending int
+}
+
+// New creates a sender posting through api. Server errors that do not come from the
+// Bot API itself, such as the HTML page of a proxy, are reported as *StatusError
+// from then on, so that they are retried like the Bot API's own.
+func New(api *tgbotapi.BotAPI) *Sender {
+	if _, wrapped := api.Client.(statusClient); !wrapped {
+		api.Client = statusClient{api.Client}
+	}
+	return &Sender{
+		api:    api,
+		global: rate.NewLimiter(globalRate, globalRate),
+		chats:  map[int64]*chatQueue{},
+	}
+}
+
+// Send posts c to chatID and returns the resulting message.
+func (s *Sender) Send(ctx context.Context, chatID int64, c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	var message tgbotapi.Message
+	err := s.do(ctx, chatID, func() error {
+		var err error
+		message, err = s.api.Send(c)
+		return err
+	})
+	return message, err
+}
+
+// Request makes a call that does not return a message, such as deleteMessage.
+func (s *Sender) Request(ctx context.Context, chatID int64, c tgbotapi.Chattable) error {
+	return s.do(ctx, chatID, func() error {
+		_, err := s.api.Request(c)
+		return err
+	})
+}
+
+// TrySend posts c to chatID only if it can go out right away: no other request to
+// the chat is pending and the rate limits allow it. Otherwise it returns ErrBusy.
+// It is tried once, without retries, which suits updates that a later one
+// supersedes, such as the progress edits of a streaming summary.
+func (s *Sender) TrySend(chatID int64, c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	queue, previous, done := s.enqueue(chatID)
+	defer s.dequeue(chatID, queue)
+
+	select {
+	case <-previous:
+		defer close(done)
+	default:
+		go func() {
+			<-previous
+			close(done)
+		}()
+		metrics.Add("dropped", 1)
+		return tgbotapi.Message{}, ErrBusy
+	}
+	// Take both tokens or neither, so that a refusal does not cost the other limiter one.
+	global := s.global.Reserve()
+	if global.Delay() > 0 {
+		global.Cancel()
+		metrics.Add("dropped", 1)
+		return tgbotapi.Message{}, ErrBusy
+	}
+	if chat := queue.limiter.Reserve(); chat.Delay() > 0 {
+		chat.Cancel()
+		global.Cancel()
+		metrics.Add("dropped", 1)
+		return tgbotapi.Message{}, ErrBusy
+	}
+
+	message, err := s.api.Send(c)
+	if err != nil {
+		metrics.Add("failed", 1)
+		return message, err
+	}
+	metrics.Add("sent", 1)
+	return message, nil
+}
+
+// do runs call after the requests made to chatID before it, within the rate limits.
+func (s *Sender) do(ctx context.Context, chatID int64, call func() error) error {
+	queue, previous, done := s.enqueue(chatID)
+	defer s.dequeue(chatID, queue)
+
+	select {
+	case <-previous:
+		defer close(done)
+	case <-ctx.Done():
+		// Release the next request only once the ones before this one are over.
+		go func() {
+			<-previous
+			close(done)
+		}()
+		return ctx.Err()
+	}
+
+	backoff := firstBackoff
+	for attempt := 1; ; attempt++ {
+		if err := queue.limiter.Wait(ctx); err != nil {
+			return err
+		}
+		if err := s.global.Wait(ctx); err != nil {
+			return err
+		}
+
+		err := call()
+		if err == nil {
+			metrics.Add("sent", 1)
+			return nil
+		}
+
+		wait, retry := retryDelay(err, backoff)
+		if !retry || attempt == maxAttempts {
+			metrics.Add("failed", 1)
+			return err
+		}
+		metrics.Add("retried", 1)
+		backoff *= 2
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// enqueue registers a request to chatID. The request may run once previous is closed,
+// and must close done when it is over.
+func (s *Sender) enqueue(chatID int64) (queue *chatQueue, previous, done chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queue, ok := s.chats[chatID]
+	if !ok {
+		s.forgetIdle()
+		limit := groupRate
+		if chatID > 0 {
+			limit = privateRate
+		}
+		queue = &chatQueue{limiter: rate.NewLimiter(limit, 1), last: make(chan struct{})}
+		close(queue.last)
+		s.chats[chatID] = queue
+	}
+	previous, done = queue.last, make(chan struct{})
+	queue.last = done
+	queue.pending++
+	return queue, previous, done
+}
+
+// dequeue unregisters a request to chatID. An idle chat is forgotten once its limiter
+// has refilled, as a new one would behave the same.
+func (s *Sender) dequeue(chatID int64, queue *chatQueue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	queue.pending--
+	if queue.pending == 0 && queue.limiter.Tokens() >= 1 {
+		delete(s.chats, chatID)
+	}
+}
+
+// forgetIdle drops the chats left idle with a full limiter. It must be called with
+// s.mu held.
+func (s *Sender) forgetIdle() {
+	for chatID, queue := range s.chats {
+		if queue.pending == 0 && queue.limiter.Tokens() >= 1 {
+			delete(s.chats, chatID)
+		}
+	}
+}
+
+// retryDelay reports whether a failed request may succeed later, and how long to
+// wait before trying again.
+func retryDelay(err error, backoff time.Duration) (time.Duration, bool) {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return backoff, true
+	}
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		return 0, false
+	}
+	switch {
+	case apiErr.Code == http.StatusTooManyRequests:
+		metrics.Add("rate_limited", 1)
+		wait := time.Duration(apiErr.RetryAfter) * time.Second
+		if wait <= 0 {
+			wait = backoff
+		}
+		return min(wait, maxRetryAfter), true
+	case apiErr.Code >= http.StatusInternalServerError:
+		return backoff, true
+	}
+	return 0, false
+}
+
+// StatusError is a server error answered without a Bot API response, e.g. by a proxy
+// in front of it.
+type StatusError struct {
+	Code   int
+	Status string
+}
+
+func (e *StatusError) Error() string {
+	return "telegram: unexpected response " + e.Status
+}
+
+// statusClient turns server errors without a JSON body into a *StatusError, which
+// tgbotapi would otherwise report as a failure to decode the body.
+type statusClient struct {
+	tgbotapi.HTTPClient
+}
+
+func (c statusClient) Do(req *http.Request) (*http.Response, error) {
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil || resp.StatusCode < http.StatusInternalServerError {
+		return resp, err
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == "application/json" {
+		return resp, nil
+	}
+	resp.Body.Close()
+	return nil, &StatusError{Code: resp.StatusCode, Status: resp.Status}
+}
@@ -0,0 +1,183 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"golang.org/x/time/rate"
+)
+
+const chatID = 42
+
+const okMessage = `{"ok":true,"result":{"message_id":7,"chat":{"id":42,"type":"private"}}}`
+
+// fakeAPI returns a sender talking to a fake Telegram API, which answers the n-th
+// sendMessage call (counting from 0) with replies[n], or with okMessage once they
+// run out. It also returns the number of sendMessage calls made so far.
+func fakeAPI(t *testing.T, replies ...string) (*Sender, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/getMe") {
+			fmt.Fprint(w, `{"ok":true,"result":{"id":1,"is_bot":true,"username":"test_bot"}}`)
+			return
+		}
+		n := int(calls.Add(1)) - 1
+		if n < len(replies) && replies[n] == proxyError {
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusBadGateway)
+			fmt.Fprint(w, "<html><body>502 Bad Gateway</body></html>")
+			return
+		}
+		if n < len(replies) {
+			fmt.Fprint(w, replies[n])
+			return
+		}
+		fmt.Fprint(w, okMessage)
+	}))
+	t.Cleanup(server.Close)
+
+	api, err := tgbotapi.NewBotAPIWithClient("token", server.URL+"/bot%s/%s", server.Client())
+	if err != nil {
+		t.Fatalf("NewBotAPIWithClient: %v", err)
+	}
+	return New(api), &calls
+}
+
+const (
+	rateLimited = `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 1","parameters":{"retry_after":1}}`
+	serverError = `{"ok":false,"error_code":502,"description":"Bad Gateway"}`
+	badRequest  = `{"ok":false,"error_code":400,"description":"Bad Request: message text is empty"}`
+	// proxyError stands for an HTML 502 page served by a proxy in front of the Bot API.
+	proxyError = "proxy error"
+)
+
+func TestSendRetries(t *testing.T) {
+	for name, tc := range map[string]struct {
+		replies  []string
+		wantErr  bool
+		calls    int32
+		minDelay time.Duration
+	}{
+		"429 waits retry_after":  {replies: []string{rateLimited}, calls: 2, minDelay: time.Second},
+		"server error backs off": {replies: []string{serverError}, calls: 2, minDelay: firstBackoff},
+		"proxy error backs off":  {replies: []string{proxyError}, calls: 2, minDelay: firstBackoff},
+		"client error fails":     {replies: []string{badRequest}, wantErr: true, calls: 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s, calls := fakeAPI(t, tc.replies...)
+
+			start := time.Now()
+			message, err := s.Send(context.Background(), chatID, tgbotapi.NewMessage(chatID, "hello"))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Send error = %v, want error %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && message.MessageID != 7 {
+				t.Errorf("message ID = %d, want 7", message.MessageID)
+			}
+			if got := calls.Load(); got != tc.calls {
+				t.Errorf("made %d calls, want %d", got, tc.calls)
+			}
+			if elapsed := time.Since(start); elapsed < tc.minDelay {
+				t.Errorf("Send returned after %s, want at least %s", elapsed, tc.minDelay)
+			}
+		})
+	}
+}
+
+func TestSendGivesUpOnCancel(t *testing.T) {
+	s, calls := fakeAPI(t, rateLimited)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := s.Send(ctx, chatID, tgbotapi.NewMessage(chatID, "hello")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Send error = %v, want the context's", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("made %d calls, want 1", got)
+	}
+}
+
+func TestTrySendDoesNotRetry(t *testing.T) {
+	s, calls := fakeAPI(t, rateLimited)
+
+	start := time.Now()
+	_, err := s.TrySend(chatID, tgbotapi.NewMessage(chatID, "hello"))
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusTooManyRequests {
+		t.Errorf("TrySend error = %v, want the 429", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("made %d calls, want 1", got)
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("TrySend returned after %s, want it not to wait", elapsed)
+	}
+}
+
+func TestTrySendDropsWhenBusy(t *testing.T) {
+	s, calls := fakeAPI(t)
+
+	if _, err := s.TrySend(chatID, tgbotapi.NewMessage(chatID, "first")); err != nil {
+		t.Fatalf("TrySend: %v", err)
+	}
+	// The private chat's limiter allows one message per second.
+	if _, err := s.TrySend(chatID, tgbotapi.NewMessage(chatID, "too soon")); !errors.Is(err, ErrBusy) {
+		t.Errorf("TrySend right after another = %v, want ErrBusy", err)
+	}
+
+	// A Send waiting for the limiter makes the chat busy until it is delivered.
+	sent := make(chan error)
+	go func() {
+		_, err := s.Send(context.Background(), chatID, tgbotapi.NewMessage(chatID, "queued"))
+		sent <- err
+	}()
+	for !pending(s) {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := s.TrySend(chatID, tgbotapi.NewMessage(chatID, "behind the queue")); !errors.Is(err, ErrBusy) {
+		t.Errorf("TrySend while a Send is pending = %v, want ErrBusy", err)
+	}
+	if err := <-sent; err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("made %d calls, want 2", got)
+	}
+}
+
+func TestTrySendRefusalKeepsChatToken(t *testing.T) {
+	s, calls := fakeAPI(t)
+
+	// Exhaust the global limiter.
+	s.global = rate.NewLimiter(rate.Every(time.Hour), 1)
+	s.global.Allow()
+	if _, err := s.TrySend(chatID, tgbotapi.NewMessage(chatID, "over the global limit")); !errors.Is(err, ErrBusy) {
+		t.Fatalf("TrySend over the global limit = %v, want ErrBusy", err)
+	}
+
+	// The chat did not spend its token on the refused message.
+	s.global = rate.NewLimiter(globalRate, globalRate)
+	if _, err := s.TrySend(chatID, tgbotapi.NewMessage(chatID, "hello")); err != nil {
+		t.Errorf("TrySend once the global limiter refilled = %v, want it sent", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("made %d calls, want 1", got)
+	}
+}
+
+// pending reports whether a request to chatID is queued or in flight.
+func pending(s *Sender) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	queue := s.chats[chatID]
+	return queue != nil && queue.pending > 0
+}
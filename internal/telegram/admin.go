@@ -0,0 +1,30 @@
+package telegram
+
+import (
+	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// isGroupAdmin reports whether the sender of a message administers its chat.
+func (b *Bot) isGroupAdmin(message *tgbotapi.Message) bool {
+	// Anonymous administrators post on behalf of the group itself.
+	if message.SenderChat != nil && message.SenderChat.ID == message.Chat.ID {
+		return true
+	}
+	if message.From == nil {
+		return false
+	}
+
+	member, err := b.app.API.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{
+			ChatID: message.Chat.ID,
+			UserID: message.From.ID,
+		},
+	})
+	if err != nil {
+		log.Printf("Error checking admin status of user %d in group %d: %v", message.From.ID, message.Chat.ID, err)
+		return false
+	}
+	return member.IsCreator() || member.IsAdministrator()
+}
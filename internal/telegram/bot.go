@@ -1,83 +1,260 @@
 package telegram
 
 import (
+	"context"
+	"fmt"
 	"log"
-	"tldr-telegram-bot/internal/db"
+	"strings"
+	"time"
 
-	"os"
+	"tldr-telegram-bot/internal/app"
+	"tldr-telegram-bot/internal/config"
+	"tldr-telegram-bot/internal/db"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"github.com/joho/godotenv"
 )
 
 type Bot struct {
-	api *tgbotapi.BotAPI
+	// app holds the dependencies shared by every handler.
+	app *app.App
+	// dispatcher runs the updates received by Start.
+	dispatcher *dispatcher
+	// tasks runs the summaries and transcriptions the updates ask for.
+	tasks *taskPool
 }
 
-func NewBot() (*Bot, error) {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatalf("Error loading .env file")
-		return nil, err
+// NewBot creates a bot that handles updates with the shared dependencies of a.
+func NewBot(a *app.App) *Bot {
+	return &Bot{app: a}
+}
+
+// Start receives updates by long polling or through a webhook, depending on
+// TELEGRAM_MODE, and dispatches them until ctx is cancelled or the update channel
+// closes, or the webhook server fails. Updates are handled with work rather than ctx, so that a shutdown stops
+// intake without cutting the handlers short; Wait drains them.
+func (b *Bot) Start(ctx, work context.Context) error {
+	myConfig := b.app.Config
+	b.dispatcher = newDispatcher(myConfig.Workers, myConfig.QueueSize)
+	b.tasks = newTaskPool(myConfig.TaskWorkers)
+
+	if myConfig.Mode == config.ModeWebhook {
+		updates, stopped, err := b.listenForWebhook(ctx, myConfig)
+		if err != nil {
+			return err
+		}
+		for {
+			select {
+			case err := <-stopped:
+				// Telegram was told these were received, so they must not be dropped.
+				for {
+					select {
+					case update := <-updates:
+						b.dispatch(work, update)
+					default:
+						return err
+					}
+				}
+			case update := <-updates:
+				b.dispatch(work, update)
+			}
+		}
 	}
 
-	token := os.Getenv("TELEGRAM_BOT_TOKEN")
-	if token == "" {
-		log.Fatal("TELEGRAM_BOT_TOKEN is required")
-		return nil, err
+	// getUpdates is refused while a webhook is registered.
+	if _, err := b.app.API.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		return fmt.Errorf("removing webhook: %w", err)
 	}
+	u := tgbotapi.NewUpdate(0)
+	u.Timeout = 60
+	updates := b.app.API.GetUpdatesChan(u)
 
-	api, err := tgbotapi.NewBotAPI(token)
-	if err != nil {
-		log.Fatalf("Failed to create Telegram bot: %v", err)
-		return nil, err
+	lastID := -1
+	for {
+		select {
+		case <-ctx.Done():
+			b.app.API.StopReceivingUpdates()
+			// The poller confirms an update on its next getUpdates, possibly while
+			// it is still buffered here, so what was received must be handled.
+			for drained := false; !drained; {
+				select {
+				case update, ok := <-updates:
+					if !ok {
+						drained = true
+						break
+					}
+					lastID = update.UpdateID
+					b.dispatch(work, update)
+				default:
+					drained = true
+				}
+			}
+			if lastID >= 0 {
+				b.confirmUpdates(lastID)
+			}
+			return nil
+		case update, ok := <-updates:
+			if !ok {
+				return nil
+			}
+			lastID = update.UpdateID
+			b.dispatch(work, update)
+		}
 	}
+}
 
-	api.Debug = false
-	log.Printf("Authorized on account %s", api.Self.UserName)
+// confirmUpdates acknowledges every update up to lastID. Telegram only considers
+// polled updates delivered once a later getUpdates asks past them, so without this
+// the last batch would be handled again after a restart.
+func (b *Bot) confirmUpdates(lastID int) {
+	if _, err := b.app.API.GetUpdates(tgbotapi.UpdateConfig{Offset: lastID + 1, Limit: 1}); err != nil {
+		log.Printf("Error confirming updates: %v", err)
+	}
+}
 
-	return &Bot{api: api}, nil
+// Wait blocks until every dispatched update has been logged and handled, including
+// the summaries and transcriptions it started.
+func (b *Bot) Wait() {
+	if b.dispatcher != nil {
+		b.dispatcher.wait()
+		b.tasks.wait()
+	}
 }
 
-func (b *Bot) Start() {
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
+// dispatch queues one update behind the earlier updates of its chat, whichever way
+// it was received. A message is logged before it is handled, so a trigger always
+// finds the messages sent before it. Summaries and transcriptions then run on the
+// task pool, leaving the queue free for the next messages.
+func (b *Bot) dispatch(ctx context.Context, update tgbotapi.Update) {
+	if update.EditedMessage != nil {
+		b.dispatcher.submit(update.EditedMessage.Chat.ID, func() { b.logEdit(ctx, update.EditedMessage) })
+		return
+	}
 
-	updates := b.api.GetUpdatesChan(u)
+	if update.Message == nil { // ignore non-message updates
+		return
+	}
 
-	for update := range updates {
-		if update.Message == nil { // ignore non-message updates
-			continue
-		}
+	b.dispatcher.submit(update.Message.Chat.ID, func() {
+		b.logMessage(ctx, update.Message)
+		b.handleMessage(ctx, update)
+	})
+}
 
-		// Log incoming messages
-		go b.logMessage(update.Message)
+func (b *Bot) logMessage(ctx context.Context, message *tgbotapi.Message) {
+	myDb := b.app.Store
+	if skipLogging(ctx, myDb, message) {
+		return
+	}
+	parsedMsg := parseMessage(message)
+
+	if err := myDb.LogMessage(ctx, parsedMsg); err != nil {
+		log.Printf("failed to insert message: %v", err)
+		return
+	}
 
-		// handle incoming messages
-		go HandleMessage(update)
+	if isSpeech(parsedMsg) {
+		b.tasks.run(func() { b.transcribeMessage(ctx, parsedMsg) })
 	}
 }
 
-func (b *Bot) logMessage(message *tgbotapi.Message) {
-	parsedMsg := db.Message{
-		MessageID: int64(message.MessageID),
-		Timestamp: message.Time(),
-		Name:      message.From.FirstName,
-		LastName:  message.From.LastName,
-		Username:  message.From.UserName,
-		GroupID:   message.Chat.ID,
-		UserID:    message.From.ID,
-		Content:   message.Text,
-	}
-
-	db.InitDB()
-	myDb := db.GetDB()
-	if myDb == nil {
-		log.Println("Database connection is nil")
+// logEdit replaces the stored text of an edited message, so summaries use its final version.
+func (b *Bot) logEdit(ctx context.Context, message *tgbotapi.Message) {
+	myDb := b.app.Store
+	if skipLogging(ctx, myDb, message) {
 		return
 	}
+	parsedMsg := parseMessage(message)
+	editedAt := time.Unix(int64(message.EditDate), 0)
 
-	if err := db.LogMessage(myDb, parsedMsg); err != nil {
-		log.Printf("failed to insert message: %v", err)
+	if err := myDb.LogEdit(ctx, parsedMsg, editedAt); err != nil {
+		log.Printf("failed to store edited message: %v", err)
+	}
+}
+
+// skipLogging reports whether the author of a message opted out of being stored.
+// Messages without an author, such as channel posts, are not stored either.
+func skipLogging(ctx context.Context, store db.MessageStore, message *tgbotapi.Message) bool {
+	if message.From == nil {
+		return true
+	}
+	optedOut, err := store.IsOptedOut(ctx, message.From.ID)
+	if err != nil {
+		log.Printf("failed to check opt-out of user %d: %v", message.From.ID, err)
+		return true
+	}
+	return optedOut
+}
+
+func parseMessage(message *tgbotapi.Message) db.Message {
+	msg := db.Message{
+		MessageID:   int64(message.MessageID),
+		Timestamp:   message.Time(),
+		GroupID:     message.Chat.ID,
+		Content:     message.Text,
+		Type:        db.MessageText,
+		Caption:     message.Caption,
+		ForwardFrom: forwardOrigin(message),
+	}
+	if message.From != nil {
+		msg.Name, msg.LastName, msg.Username = message.From.FirstName, message.From.LastName, message.From.UserName
+		msg.UserID = message.From.ID
+	}
+	if message.ReplyToMessage != nil {
+		msg.ReplyToMessageID = int64(message.ReplyToMessage.MessageID)
+	}
+
+	switch {
+	case len(message.Photo) > 0:
+		// Sizes are listed from the smallest to the largest.
+		msg.Type, msg.FileID = db.MessagePhoto, message.Photo[len(message.Photo)-1].FileID
+	case message.Video != nil:
+		msg.Type, msg.FileID = db.MessageVideo, message.Video.FileID
+	case message.Animation != nil: // also sets Document, so it goes first
+		msg.Type, msg.FileID = db.MessageAnimation, message.Animation.FileID
+	case message.Document != nil:
+		msg.Type, msg.FileID = db.MessageDocument, message.Document.FileID
+		msg.Content = message.Document.FileName
+	case message.Audio != nil:
+		msg.Type, msg.FileID = db.MessageAudio, message.Audio.FileID
+		msg.Content = strings.TrimSpace(message.Audio.Performer + " " + message.Audio.Title)
+	case message.Voice != nil:
+		msg.Type, msg.FileID = db.MessageVoice, message.Voice.FileID
+	case message.VideoNote != nil:
+		msg.Type, msg.FileID = db.MessageVideoNote, message.VideoNote.FileID
+	case message.Sticker != nil:
+		msg.Type, msg.FileID = db.MessageSticker, message.Sticker.FileID
+		msg.Content = message.Sticker.Emoji
+	case message.Poll != nil:
+		msg.Type, msg.Content = db.MessagePoll, describePoll(message.Poll)
+	case message.Venue != nil: // also sets Location, so it goes first
+		msg.Type, msg.Content = db.MessageLocation, strings.TrimSpace(message.Venue.Title+", "+message.Venue.Address)
+	case message.Location != nil:
+		msg.Type = db.MessageLocation
+		msg.Content = fmt.Sprintf("%.5f, %.5f", message.Location.Latitude, message.Location.Longitude)
+	case message.Contact != nil:
+		msg.Type = db.MessageContact
+		msg.Content = strings.TrimSpace(message.Contact.FirstName + " " + message.Contact.LastName)
+	}
+	return msg
+}
+
+// forwardOrigin names the original sender of a forwarded message.
+func forwardOrigin(message *tgbotapi.Message) string {
+	switch {
+	case message.ForwardFrom != nil:
+		return strings.TrimSpace(message.ForwardFrom.FirstName + " " + message.ForwardFrom.LastName)
+	case message.ForwardFromChat != nil:
+		return message.ForwardFromChat.Title
+	default:
+		return message.ForwardSenderName
+	}
+}
+
+// describePoll renders a poll as its question followed by the options.
+func describePoll(poll *tgbotapi.Poll) string {
+	options := make([]string, len(poll.Options))
+	for i, option := range poll.Options {
+		options[i] = option.Text
 	}
+	return fmt.Sprintf("%s (%s)", poll.Question, strings.Join(options, " / "))
 }
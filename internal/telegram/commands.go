@@ -0,0 +1,82 @@
+package telegram
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"tldr-telegram-bot/internal/db"
+)
+
+// parseTldrArgs turns the arguments of /tldr into a message query. Supported forms:
+//
+//	/tldr              the last window
+//	/tldr 2h           a duration back from now (also 90m, 1h30m, 2d)
+//	/tldr 200          the last N messages
+//	/tldr since 09:00  since the last occurrence of that time of day
+//	/tldr today        since midnight
+func parseTldrArgs(args string, now time.Time, window time.Duration) (db.Query, error) {
+	fields := strings.Fields(strings.ToLower(args))
+
+	switch {
+	case len(fields) == 0:
+		return db.Query{Since: now.Add(-window)}, nil
+
+	case len(fields) == 1 && fields[0] == "today":
+		year, month, day := now.Date()
+		return db.Query{Since: time.Date(year, month, day, 0, 0, 0, 0, now.Location())}, nil
+
+	case len(fields) == 2 && fields[0] == "since":
+		since, err := parseTimeOfDay(fields[1], now)
+		if err != nil {
+			return db.Query{}, err
+		}
+		return db.Query{Since: since}, nil
+
+	case len(fields) == 1:
+		if n, err := strconv.Atoi(fields[0]); err == nil {
+			if n <= 0 {
+				return db.Query{}, fmt.Errorf("message count must be positive: %d", n)
+			}
+			return db.Query{Limit: n}, nil
+		}
+		d, err := parseWindow(fields[0])
+		if err != nil {
+			return db.Query{}, err
+		}
+		return db.Query{Since: now.Add(-d)}, nil
+	}
+
+	return db.Query{}, fmt.Errorf("unrecognized arguments: %q", args)
+}
+
+// parseWindow parses a positive duration, accepting a "d" suffix for days.
+func parseWindow(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid duration: %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration: %q", value)
+	}
+	return d, nil
+}
+
+// parseTimeOfDay resolves "HH:MM" to its most recent occurrence at or before now.
+func parseTimeOfDay(value string, now time.Time) (time.Time, error) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time of day: %q", value)
+	}
+	year, month, day := now.Date()
+	t := time.Date(year, month, day, clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	if t.After(now) {
+		t = t.AddDate(0, 0, -1)
+	}
+	return t, nil
+}
@@ -0,0 +1,83 @@
+package telegram
+
+import (
+	"testing"
+	"time"
+
+	"tldr-telegram-bot/internal/db"
+)
+
+func TestParseTldrArgs(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+	// 07:30 UTC; times of day refer to the group's clock, where it is 08:30.
+	now := time.Date(2026, 3, 10, 8, 30, 0, 0, berlin)
+	const window = 4 * time.Hour
+
+	for _, tc := range []struct {
+		args    string
+		want    db.Query
+		wantErr bool
+	}{
+		{args: "", want: db.Query{Since: now.Add(-window)}},
+		{args: "2h", want: db.Query{Since: now.Add(-2 * time.Hour)}},
+		{args: "2H", want: db.Query{Since: now.Add(-2 * time.Hour)}},
+		{args: "90m", want: db.Query{Since: now.Add(-90 * time.Minute)}},
+		{args: "1h30m", want: db.Query{Since: now.Add(-90 * time.Minute)}},
+		{args: "2d", want: db.Query{Since: now.Add(-48 * time.Hour)}},
+		{args: "50", want: db.Query{Limit: 50}},
+		{args: "  50  ", want: db.Query{Limit: 50}},
+		{args: "today", want: db.Query{Since: time.Date(2026, 3, 10, 0, 0, 0, 0, berlin)}},
+		{args: "since 07:15", want: db.Query{Since: time.Date(2026, 3, 10, 7, 15, 0, 0, berlin)}},
+		{args: "since 08:30", want: db.Query{Since: now}},
+		{args: "since 09:00", want: db.Query{Since: time.Date(2026, 3, 9, 9, 0, 0, 0, berlin)}},
+
+		{args: "0", wantErr: true},
+		{args: "-5", wantErr: true},
+		{args: "0d", wantErr: true},
+		{args: "-2h", wantErr: true},
+		{args: "0s", wantErr: true},
+		{args: "xd", wantErr: true},
+		{args: "2 hours", wantErr: true},
+		{args: "banana", wantErr: true},
+		{args: "since", wantErr: true},
+		{args: "since 25:00", wantErr: true},
+		{args: "since 9am", wantErr: true},
+		{args: "today 2h", wantErr: true},
+	} {
+		t.Run(tc.args, func(t *testing.T) {
+			got, err := parseTldrArgs(tc.args, now, window)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if !got.Since.Equal(tc.want.Since) || got.Limit != tc.want.Limit {
+				t.Errorf("query = %+v, want %+v", got, tc.want)
+			}
+			if !got.Since.IsZero() && got.Since.Location() != berlin {
+				t.Errorf("since is in %s, want the group's timezone", got.Since.Location())
+			}
+		})
+	}
+}
+
+func TestParseTldrArgsAcrossDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+	// Clocks went forward an hour at 02:00 that night, so midnight was only 9 hours ago.
+	now := time.Date(2026, 3, 29, 10, 0, 0, 0, berlin)
+
+	got, err := parseTldrArgs("today", now, time.Hour)
+	if err != nil {
+		t.Fatalf("parseTldrArgs: %v", err)
+	}
+	if elapsed := now.Sub(got.Since); elapsed != 9*time.Hour {
+		t.Errorf("today started %s ago, want 9h", elapsed)
+	}
+}
@@ -0,0 +1,171 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"tldr-telegram-bot/internal/config"
+	"tldr-telegram-bot/internal/db"
+	"tldr-telegram-bot/internal/scheduler"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// parseWeekday accepts the lower-cased English name of a weekday, in full or as
+// its three-letter abbreviation.
+func parseWeekday(name string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		full := strings.ToLower(day.String())
+		if name == full || name == full[:3] {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// SendDigest posts the summary of everything a group said between since and until.
+// It is called by the scheduler for every due digest, and returns an error if the
+// digest could not be posted, so that the next one covers this range again.
+func (b *Bot) SendDigest(ctx context.Context, groupID int64, since, until time.Time) error {
+	if !b.isAuthorizedGroup(groupID) {
+		logUnauthorizedAttempt(groupID)
+		return fmt.Errorf("group %d is not authorized", groupID)
+	}
+
+	myConfig, err := b.loadGroupConfig(ctx, groupID)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	loc := myConfig.Location()
+	title := localize(myConfig.Lang, "digest_title", since.In(loc).Format("2006-01-02 15:04"), until.In(loc).Format("2006-01-02 15:04"))
+	return b.collectAndSummarizeMessages(ctx, groupID, db.Query{Since: since, Until: until}, summaryOptions{title: title, quiet: true})
+}
+
+// handleDigestCommand shows or changes the digest schedule of a group. Supported forms:
+//
+//	/digest                              show the current schedule
+//	/digest off                          disable the digest
+//	/digest daily 18:00 [timezone]       every day at 18:00
+//	/digest weekly mon 09:00 [timezone]  every Monday at 09:00
+func (b *Bot) handleDigestCommand(ctx context.Context, message *tgbotapi.Message) {
+	myConfig, err := b.loadGroupConfig(ctx, message.Chat.ID)
+	if err != nil {
+		log.Printf("Error loading config: %v", err)
+		return
+	}
+	lang := myConfig.Lang
+	myDb := b.app.Store
+
+	fields := strings.Fields(strings.ToLower(message.CommandArguments()))
+	if len(fields) == 0 {
+		schedule, err := myDb.GetDigestSchedule(ctx, message.Chat.ID)
+		if err != nil {
+			log.Printf("Error loading digest schedule: %v", err)
+			return
+		}
+		if schedule == nil {
+			b.replyText(ctx, message, localize(lang, "digest_off"))
+			return
+		}
+		b.replyText(ctx, message, localize(lang, "digest_status", describeSchedule(*schedule), schedule.NextRun.In(scheduleLocation(*schedule)).Format("2006-01-02 15:04 MST")))
+		return
+	}
+
+	if !b.isGroupAdmin(message) {
+		b.replyText(ctx, message, localize(lang, "admin_only"))
+		return
+	}
+
+	if fields[0] == "off" {
+		if err := myDb.DeleteDigestSchedule(ctx, message.Chat.ID); err != nil {
+			log.Printf("Error deleting digest schedule: %v", err)
+			return
+		}
+		b.replyText(ctx, message, localize(lang, "digest_off"))
+		return
+	}
+
+	// The timezone keeps its original case, e.g. "America/Sao_Paulo".
+	schedule, err := parseDigestArgs(fields, strings.Fields(message.CommandArguments()), myConfig.Timezone)
+	if err != nil {
+		b.replyText(ctx, message, localize(lang, "digest_usage", err))
+		return
+	}
+	schedule.GroupID = message.Chat.ID
+	schedule.NextRun, err = scheduler.NextRun(schedule, time.Now())
+	if err != nil {
+		b.replyText(ctx, message, localize(lang, "digest_usage", err))
+		return
+	}
+
+	if err := myDb.SaveDigestSchedule(ctx, schedule); err != nil {
+		log.Printf("Error saving digest schedule: %v", err)
+		return
+	}
+	b.replyText(ctx, message, localize(lang, "digest_status", describeSchedule(schedule), schedule.NextRun.In(scheduleLocation(schedule)).Format("2006-01-02 15:04 MST")))
+}
+
+// parseDigestArgs parses the lower-cased fields of /digest; original holds the same
+// fields with their case preserved. Schedules without a timezone use timezone.
+func parseDigestArgs(fields, original []string, timezone string) (db.DigestSchedule, error) {
+	schedule := db.DigestSchedule{Frequency: fields[0], Timezone: timezone}
+
+	rest := fields[1:]
+	switch schedule.Frequency {
+	case db.DigestDaily:
+	case db.DigestWeekly:
+		if len(rest) == 0 {
+			return schedule, fmt.Errorf("missing weekday")
+		}
+		weekday, ok := parseWeekday(rest[0])
+		if !ok {
+			return schedule, fmt.Errorf("invalid weekday: %q", rest[0])
+		}
+		schedule.Weekday = weekday
+		rest = rest[1:]
+	default:
+		return schedule, fmt.Errorf("unknown frequency: %q", fields[0])
+	}
+
+	if len(rest) == 0 {
+		return schedule, fmt.Errorf("missing time of day")
+	}
+	clock, err := time.Parse("15:04", rest[0])
+	if err != nil {
+		return schedule, fmt.Errorf("invalid time of day: %q", rest[0])
+	}
+	schedule.Hour, schedule.Minute = clock.Hour(), clock.Minute()
+
+	switch len(rest) {
+	case 1:
+	case 2:
+		timezone := original[len(original)-1]
+		if !config.IsValidTimezone(timezone) {
+			return schedule, fmt.Errorf("invalid timezone: %q", timezone)
+		}
+		schedule.Timezone = timezone
+	default:
+		return schedule, fmt.Errorf("too many arguments")
+	}
+	return schedule, nil
+}
+
+func describeSchedule(schedule db.DigestSchedule) string {
+	clock := fmt.Sprintf("%02d:%02d %s", schedule.Hour, schedule.Minute, schedule.Timezone)
+	if schedule.Frequency == db.DigestWeekly {
+		return fmt.Sprintf("weekly, %s %s", schedule.Weekday, clock)
+	}
+	return "daily, " + clock
+}
+
+func scheduleLocation(schedule db.DigestSchedule) *time.Location {
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
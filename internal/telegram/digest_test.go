@@ -0,0 +1,52 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"tldr-telegram-bot/internal/db"
+)
+
+func TestParseDigestArgs(t *testing.T) {
+	const groupTimezone = "Europe/Berlin"
+	for _, tc := range []struct {
+		args    string
+		want    db.DigestSchedule
+		wantErr bool
+	}{
+		{args: "daily 09:00", want: db.DigestSchedule{Frequency: db.DigestDaily, Hour: 9, Timezone: groupTimezone}},
+		{args: "DAILY 18:45", want: db.DigestSchedule{Frequency: db.DigestDaily, Hour: 18, Minute: 45, Timezone: groupTimezone}},
+		{args: "daily 07:30 America/Sao_Paulo", want: db.DigestSchedule{Frequency: db.DigestDaily, Hour: 7, Minute: 30, Timezone: "America/Sao_Paulo"}},
+		{args: "weekly mon 09:00", want: db.DigestSchedule{Frequency: db.DigestWeekly, Weekday: time.Monday, Hour: 9, Timezone: groupTimezone}},
+		{args: "weekly Friday 17:00", want: db.DigestSchedule{Frequency: db.DigestWeekly, Weekday: time.Friday, Hour: 17, Timezone: groupTimezone}},
+		{args: "weekly Thursday 08:15", want: db.DigestSchedule{Frequency: db.DigestWeekly, Weekday: time.Thursday, Hour: 8, Minute: 15, Timezone: groupTimezone}},
+		{args: "weekly sun 00:00 UTC", want: db.DigestSchedule{Frequency: db.DigestWeekly, Weekday: time.Sunday, Timezone: "UTC"}},
+
+		{args: "hourly 09:00", wantErr: true},
+		{args: "daily", wantErr: true},
+		{args: "daily 9am", wantErr: true},
+		{args: "daily 24:00", wantErr: true},
+		{args: "daily 09:00 Mars/Olympus", wantErr: true},
+		{args: "daily 09:00 UTC extra", wantErr: true},
+		{args: "weekly", wantErr: true},
+		{args: "weekly 09:00", wantErr: true},
+		{args: "weekly someday 09:00", wantErr: true},
+		{args: "weekly monkey 09:00", wantErr: true},
+		{args: "weekly frida 09:00", wantErr: true},
+		{args: "weekly mo 09:00", wantErr: true},
+		{args: "weekly mon", wantErr: true},
+	} {
+		t.Run(tc.args, func(t *testing.T) {
+			original := strings.Fields(tc.args)
+			fields := strings.Fields(strings.ToLower(tc.args))
+			got, err := parseDigestArgs(fields, original, groupTimezone)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && got != tc.want {
+				t.Errorf("schedule = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package telegram
+
+import (
+	"expvar"
+	"log"
+	"runtime/debug"
+	"sync"
+)
+
+// metrics exposes the state of the dispatcher under "dispatcher" in /debug/vars:
+//
+//	workers        number of workers
+//	capacity       how many jobs may be queued or running before intake blocks
+//	queued         jobs waiting for a worker or running
+//	running        jobs being run
+//	chats          chats with queued jobs
+//	processed      jobs finished since startup
+//	blocked        jobs that had to wait for room in the queue
+//	tasks          long-running tasks, such as summaries, being run
+//	tasks_blocked  tasks that had to wait for a free slot
+//	panics         jobs and tasks that panicked
+var metrics = expvar.NewMap("dispatcher")
+
+// dispatcher runs jobs on a fixed number of workers. Jobs of the same chat run one
+// at a time, in the order they were submitted; jobs of different chats run in parallel.
+type dispatcher struct {
+	// slots holds a token per job queued or running, bounding the backlog.
+	slots chan struct{}
+	// ready lists the chats whose next job may run. A chat is listed at most once,
+	// so it never holds more entries than slots.
+	ready chan int64
+
+	mu     sync.Mutex
+	queues map[int64][]func() // the first job of a queue is ready or running
+	jobs   sync.WaitGroup
+}
+
+// newDispatcher starts workers goroutines serving at most capacity jobs at a time.
+func newDispatcher(workers, capacity int) *dispatcher {
+	d := &dispatcher{
+		slots:  make(chan struct{}, capacity),
+		ready:  make(chan int64, capacity),
+		queues: map[int64][]func(){},
+	}
+	setMetric("workers", int64(workers))
+	setMetric("capacity", int64(capacity))
+	for i := 0; i < workers; i++ {
+		go d.work()
+	}
+	return d
+}
+
+// submit queues job behind the other jobs of the chat. It blocks while the queue is
+// full, which in turn stops the intake of updates.
+func (d *dispatcher) submit(chatID int64, job func()) {
+	select {
+	case d.slots <- struct{}{}:
+	default:
+		metrics.Add("blocked", 1)
+		d.slots <- struct{}{}
+	}
+	d.jobs.Add(1)
+	metrics.Add("queued", 1)
+
+	d.mu.Lock()
+	queue, active := d.queues[chatID]
+	d.queues[chatID] = append(queue, job)
+	if !active {
+		metrics.Add("chats", 1)
+	}
+	d.mu.Unlock()
+
+	if !active {
+		d.ready <- chatID
+	}
+}
+
+// wait blocks until every submitted job has finished.
+func (d *dispatcher) wait() {
+	d.jobs.Wait()
+}
+
+func (d *dispatcher) work() {
+	for chatID := range d.ready {
+		d.mu.Lock()
+		job := d.queues[chatID][0]
+		d.mu.Unlock()
+
+		metrics.Add("running", 1)
+		runRecovered(job)
+		metrics.Add("running", -1)
+
+		d.mu.Lock()
+		queue := d.queues[chatID][1:]
+		if len(queue) == 0 {
+			delete(d.queues, chatID)
+			metrics.Add("chats", -1)
+		} else {
+			d.queues[chatID] = queue
+		}
+		d.mu.Unlock()
+
+		metrics.Add("queued", -1)
+		metrics.Add("processed", 1)
+		<-d.slots
+		d.jobs.Done()
+
+		// Go to the back of the line, so a busy chat does not starve the others.
+		if len(queue) > 0 {
+			d.ready <- chatID
+		}
+	}
+}
+
+// runRecovered runs job, logging rather than propagating a panic, so that a failing
+// handler neither kills its worker nor leaves its chat's queue stuck.
+func runRecovered(job func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			metrics.Add("panics", 1)
+			log.Printf("panic handling update: %v\n%s", r, debug.Stack())
+		}
+	}()
+	job()
+}
+
+// setMetric replaces the value of a gauge, rather than adding to it like Add.
+func setMetric(key string, value int64) {
+	v := new(expvar.Int)
+	v.Set(value)
+	metrics.Set(key, v)
+}
+
+// taskPool runs long-running work, such as summaries and transcriptions, outside the
+// per-chat queues, so a chat keeps logging its messages while a summary is generated.
+type taskPool struct {
+	// slots holds a token per running task, bounding how many run at once.
+	slots chan struct{}
+	tasks sync.WaitGroup
+}
+
+func newTaskPool(size int) *taskPool {
+	return &taskPool{slots: make(chan struct{}, size)}
+}
+
+// run starts task in the background. It blocks while every slot is taken, which in
+// turn holds up the queue of the chat submitting it.
+func (p *taskPool) run(task func()) {
+	select {
+	case p.slots <- struct{}{}:
+	default:
+		metrics.Add("tasks_blocked", 1)
+		p.slots <- struct{}{}
+	}
+	p.tasks.Add(1)
+	metrics.Add("tasks", 1)
+
+	go func() {
+		defer func() {
+			metrics.Add("tasks", -1)
+			<-p.slots
+			p.tasks.Done()
+		}()
+		runRecovered(task)
+	}()
+}
+
+// wait blocks until every started task has finished.
+func (p *taskPool) wait() {
+	p.tasks.Wait()
+}
@@ -0,0 +1,108 @@
+package telegram
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitTimeout fails the test unless d finishes its jobs within a second.
+func waitTimeout(t *testing.T, d *dispatcher) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		d.wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("jobs did not finish")
+	}
+}
+
+func TestDispatcherKeepsChatOrder(t *testing.T) {
+	d := newDispatcher(4, 16)
+
+	var mu sync.Mutex
+	got := map[int64][]int{}
+	const chats, jobs = 3, 50
+	for i := 0; i < jobs; i++ {
+		for chatID := int64(1); chatID <= chats; chatID++ {
+			i, chatID := i, chatID
+			d.submit(chatID, func() {
+				mu.Lock()
+				got[chatID] = append(got[chatID], i)
+				mu.Unlock()
+			})
+		}
+	}
+	waitTimeout(t, d)
+
+	for chatID := int64(1); chatID <= chats; chatID++ {
+		if len(got[chatID]) != jobs {
+			t.Fatalf("chat %d ran %d jobs, want %d", chatID, len(got[chatID]), jobs)
+		}
+		for i, job := range got[chatID] {
+			if job != i {
+				t.Fatalf("chat %d ran job %d in position %d", chatID, job, i)
+			}
+		}
+	}
+}
+
+func TestDispatcherRunsChatsInParallel(t *testing.T) {
+	d := newDispatcher(2, 4)
+
+	// The job of chat 1 only returns once the job of chat 2 has run.
+	ran := make(chan struct{})
+	d.submit(1, func() {
+		select {
+		case <-ran:
+		case <-time.After(time.Second):
+			t.Error("chat 2 did not run while chat 1 was busy")
+		}
+	})
+	d.submit(2, func() { close(ran) })
+	waitTimeout(t, d)
+}
+
+func TestDispatcherSubmitBlocksAtCapacity(t *testing.T) {
+	d := newDispatcher(1, 2)
+
+	release := make(chan struct{})
+	d.submit(1, func() { <-release })
+	d.submit(1, func() {})
+
+	submitted := make(chan struct{})
+	go func() {
+		d.submit(2, func() {})
+		close(submitted)
+	}()
+	select {
+	case <-submitted:
+		t.Fatal("submit returned with the queue full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-submitted:
+	case <-time.After(time.Second):
+		t.Fatal("submit still blocked once the queue drained")
+	}
+	waitTimeout(t, d)
+}
+
+func TestDispatcherRecoversFromPanics(t *testing.T) {
+	d := newDispatcher(1, 4)
+
+	ran := false
+	d.submit(1, func() { panic("handler bug") })
+	d.submit(1, func() { ran = true })
+	waitTimeout(t, d)
+
+	if !ran {
+		t.Error("the job after a panicking one did not run")
+	}
+}
@@ -0,0 +1,63 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleForgetCommand deletes stored messages of the group. Supported forms:
+//
+//	/forget      (as a reply) the replied-to message
+//	/forget all  everything stored for the group
+//	/forget 2h   the messages of the last two hours (also 90m, 2d, ...)
+func (b *Bot) handleForgetCommand(ctx context.Context, message *tgbotapi.Message) {
+	myConfig, err := b.loadGroupConfig(ctx, message.Chat.ID)
+	if err != nil {
+		log.Printf("Error loading config: %v", err)
+		return
+	}
+
+	if !b.isGroupAdmin(message) {
+		b.replyText(ctx, message, localize(myConfig.Lang, "admin_only"))
+		return
+	}
+
+	myDb := b.app.Store
+	args := strings.ToLower(strings.TrimSpace(message.CommandArguments()))
+	var deleted int64
+	switch {
+	case args == "" && message.ReplyToMessage != nil:
+		if err := myDb.DeleteMessage(ctx, message.Chat.ID, int64(message.ReplyToMessage.MessageID)); err != nil {
+			log.Printf("Error deleting message: %v", err)
+			return
+		}
+		deleted = 1
+
+	case args == "all":
+		deleted, err = myDb.DeleteMessages(ctx, message.Chat.ID, time.Time{}, time.Time{})
+
+	case args != "":
+		window, parseErr := parseWindow(args)
+		if parseErr != nil {
+			b.replyText(ctx, message, localize(myConfig.Lang, "forget_usage", parseErr))
+			return
+		}
+		deleted, err = myDb.DeleteMessages(ctx, message.Chat.ID, time.Now().Add(-window), time.Time{})
+
+	default:
+		b.replyText(ctx, message, localize(myConfig.Lang, "forget_usage", fmt.Errorf("nothing to forget")))
+		return
+	}
+	if err != nil {
+		log.Printf("Error deleting messages: %v", err)
+		return
+	}
+
+	log.Printf("Deleted %d message(s) from group %d on request of user %d", deleted, message.Chat.ID, message.From.ID)
+	b.replyText(ctx, message, localize(myConfig.Lang, "forget_done", deleted))
+}
@@ -1,45 +1,157 @@
 package telegram
 
 import (
+	"context"
 	"fmt"
 	"log"
-	"os"
 	"strings"
+	"time"
 
-	"tldr-telegram-bot/internal/config"
 	"tldr-telegram-bot/internal/db"
 	"tldr-telegram-bot/internal/llm"
+	"tldr-telegram-bot/internal/redact"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 var triggerWords = []string{"resuma", "resume", "tldr", "summary", "toguro por favor", "toguro please", "professor toguro", "professor toguro por favor", "professor toguro please", "toguro", "toguro por favor", "toguro please", "toguro professor", "toguro professor por favor", "toguro professor please"}
 
-func HandleMessage(update tgbotapi.Update) {
-	if update.Message == nil || update.Message.ReplyToMessage == nil {
+func (b *Bot) handleMessage(ctx context.Context, update tgbotapi.Update) {
+	// Commands act on behalf of their author, so messages without one are ignored.
+	if update.Message == nil || update.Message.From == nil {
 		return
 	}
 
-	if !isAuthorizedGroup(update.Message.Chat.ID) {
+	if !update.Message.IsCommand() && update.Message.ReplyToMessage == nil {
+		return
+	}
+
+	// Opting out is personal, so it works in any chat, including private ones.
+	switch update.Message.Command() {
+	case "optout":
+		b.handleOptOutCommand(ctx, update.Message)
+		return
+	case "optin":
+		b.handleOptInCommand(ctx, update.Message)
+		return
+	}
+
+	if !b.isAuthorizedGroup(update.Message.Chat.ID) {
 		logUnauthorizedAttempt(update.Message.Chat.ID)
 		return
 	}
 
+	if update.Message.IsCommand() {
+		b.handleCommand(ctx, update.Message)
+		return
+	}
+
 	if isTriggerWord(update.Message.Text) {
 		log.Printf("Trigger word detected in group %d", update.Message.Chat.ID)
-		collectAndSummarizeMessages(update)
+		myConfig, err := b.loadGroupConfig(ctx, update.Message.Chat.ID)
+		if err != nil {
+			log.Printf("Error loading config: %v", err)
+			return
+		}
+		query := db.Query{AnchorMessageID: int64(update.Message.ReplyToMessage.MessageID), Window: myConfig.Window}
+		b.summarizeLater(ctx, update.Message.Chat.ID, query, summaryOptions{})
+	}
+}
+
+func (b *Bot) handleCommand(ctx context.Context, message *tgbotapi.Message) {
+	switch message.Command() {
+	case "tldr":
+		b.handleTldrCommand(ctx, message)
+	case "digest":
+		b.handleDigestCommand(ctx, message)
+	case "settings":
+		b.handleSettingsCommand(ctx, message)
+	case "forget":
+		b.handleForgetCommand(ctx, message)
 	}
 }
 
-func isAuthorizedGroup(groupID int64) bool {
-	config, err := config.LoadConfig()
+// handleTldrCommand summarizes the range described by the command arguments, or the
+// default window after the replied-to message when there are none.
+func (b *Bot) handleTldrCommand(ctx context.Context, message *tgbotapi.Message) {
+	myConfig, err := b.loadGroupConfig(ctx, message.Chat.ID)
 	if err != nil {
 		log.Printf("Error loading config: %v", err)
-		return false
+		return
+	}
+
+	args := message.CommandArguments()
+	if strings.TrimSpace(args) == "" && message.ReplyToMessage != nil {
+		query := db.Query{AnchorMessageID: int64(message.ReplyToMessage.MessageID), Window: myConfig.Window}
+		b.summarizeLater(ctx, message.Chat.ID, query, summaryOptions{})
+		return
+	}
+
+	fields := strings.Fields(args)
+	switch {
+	case len(fields) == 1 && strings.EqualFold(fields[0], "start"):
+		if message.ReplyToMessage == nil {
+			b.replyText(ctx, message, localize(myConfig.Lang, "range_need_reply"))
+			return
+		}
+		markRangeStart(message.Chat.ID, message.From.ID, int64(message.ReplyToMessage.MessageID))
+		b.replyText(ctx, message, localize(myConfig.Lang, "range_started"))
+		return
+
+	case len(fields) == 1 && strings.EqualFold(fields[0], "end"):
+		start, ok := takeRangeStart(message.Chat.ID, message.From.ID)
+		if !ok {
+			b.replyText(ctx, message, localize(myConfig.Lang, "range_no_start"))
+			return
+		}
+		query := db.Query{AnchorMessageID: start}
+		if message.ReplyToMessage != nil {
+			query.EndMessageID = int64(message.ReplyToMessage.MessageID)
+		}
+		b.summarizeLater(ctx, message.Chat.ID, query, summaryOptions{})
+		return
+
+	case len(fields) > 0 && isMessageLink(fields[0]):
+		query, err := linkRangeQuery(message, fields)
+		if err != nil {
+			b.replyText(ctx, message, localize(myConfig.Lang, "range_bad_link", err))
+			return
+		}
+		b.summarizeLater(ctx, message.Chat.ID, query, summaryOptions{})
+		return
+	}
+
+	// "since 09:00" and "today" refer to the group's clock.
+	query, err := parseTldrArgs(args, time.Now().In(myConfig.Location()), myConfig.Window)
+	if err != nil {
+		b.replyText(ctx, message, localize(myConfig.Lang, "tldr_usage", err))
+		return
+	}
+	b.summarizeLater(ctx, message.Chat.ID, query, summaryOptions{})
+}
+
+// linkRangeQuery builds a range from two message links, or from one link and the replied-to message.
+func linkRangeQuery(message *tgbotapi.Message, links []string) (db.Query, error) {
+	var ids []int64
+	for _, link := range links {
+		id, err := parseMessageLink(link, message.Chat.ID, message.Chat.UserName)
+		if err != nil {
+			return db.Query{}, err
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 1 && message.ReplyToMessage != nil {
+		ids = append(ids, int64(message.ReplyToMessage.MessageID))
+	}
+	if len(ids) != 2 {
+		return db.Query{}, fmt.Errorf("expected two messages: reply to one and link the other, or pass two links")
 	}
+	return db.Query{AnchorMessageID: ids[0], EndMessageID: ids[1]}, nil
+}
 
+func (b *Bot) isAuthorizedGroup(groupID int64) bool {
 	// Check if the group ID is in the list of authorized groups
-	for _, authorizedGroup := range config.AuthorizedGroups {
+	for _, authorizedGroup := range b.app.Config.AuthorizedGroups {
 		if authorizedGroup == groupID {
 			return true
 		}
@@ -61,76 +173,242 @@ func isTriggerWord(message string) bool {
 	return false
 }
 
-func collectAndSummarizeMessages(update tgbotapi.Update) {
-	myDb := db.GetDB()
+// summarizeLater runs collectAndSummarizeMessages on the task pool. The messages it
+// reads are already logged, so the chat's queue can move on meanwhile.
+func (b *Bot) summarizeLater(ctx context.Context, chatID int64, query db.Query, opts summaryOptions) {
+	b.tasks.run(func() {
+		if err := b.collectAndSummarizeMessages(ctx, chatID, query, opts); err != nil {
+			log.Printf("Error summarizing chat %d: %v", chatID, err)
+		}
+	})
+}
+
+// summaryOptions tweaks how collectAndSummarizeMessages presents its result.
+type summaryOptions struct {
+	// title is shown above the summary.
+	title string
+	// quiet skips the reply when there is nothing to summarize.
+	quiet bool
+}
+
+// collectAndSummarizeMessages posts the summary of the messages selected by query.
+// It returns an error if no summary could be posted.
+func (b *Bot) collectAndSummarizeMessages(ctx context.Context, chatID int64, query db.Query, opts summaryOptions) error {
+	myDb := b.app.Store
 
-	myConfig, err := config.LoadConfig()
+	myConfig, err := b.loadGroupConfig(ctx, chatID)
 	if err != nil {
-		log.Printf("Error loading config: %v", err)
-		return
+		return fmt.Errorf("loading config: %w", err)
 	}
 
-	messages, err := db.GetMessages(myDb, int64(update.Message.ReplyToMessage.MessageID), update.Message.Chat.ID)
+	// Ask for one message more than is summarized, to tell when older ones are left out.
+	if query.Limit == 0 || query.Limit > db.MaxMessages {
+		query.Limit = db.MaxMessages + 1
+	}
+	messages, err := myDb.GetMessages(ctx, chatID, query)
 	if err != nil {
-		log.Printf("Error collecting messages: %v", err)
-		return
+		return fmt.Errorf("collecting messages: %w", err)
+	}
+	truncated := len(messages) > db.MaxMessages
+	if truncated {
+		messages = messages[len(messages)-db.MaxMessages:]
+	}
+
+	// Messages stored before their author opted out must not reach the prompt.
+	excluded, err := b.excludedUsers(ctx, messages)
+	if err != nil {
+		return fmt.Errorf("loading opted-out users: %w", err)
 	}
 
-	if len(messages) == 0 {
+	// Keep one message per line: chunking and the extractive fallback rely on it.
+	concatenatedText := strings.TrimSpace(formatMessages(messages, excluded, enrichments{}))
+	if concatenatedText == "" {
 		log.Println("No messages found for summarization.")
-		return
+		if opts.quiet {
+			return nil
+		}
+		return b.sendSummary(ctx, chatID, localize(myConfig.Lang, "no_messages"))
 	}
 
-	concatenatedText := formatMessages(messages)
-	concatenatedText = strings.ReplaceAll(concatenatedText, "\n", " ")
-	concatenatedText = strings.TrimSpace(concatenatedText)
-	fmt.Println("Concatenated text for summarization:", concatenatedText)
+	log.Printf("Summarizing %d message(s), %d byte(s) of text, for chat %d", len(messages), len(concatenatedText), chatID)
 
-	if os.Getenv("LOCAL_MODEL") != "true" {
-		summary, err := llm.SummarizeGemini(concatenatedText, myConfig.Lang)
-		if err != nil {
-			log.Printf("Error summarizing messages: %v", err)
-			return
-		}
-		sendSummary(update.Message.Chat.ID, summary)
-		return
+	chain, err := b.app.SummarizerFor(myConfig)
+	if err != nil {
+		return fmt.Errorf("creating summarizer: %w", err)
 	}
-	summary, err := llm.Summarize(concatenatedText, myConfig.Lang)
+
+	// Post a placeholder that is edited as the summary streams in.
+	live, err := newLiveMessage(ctx, b, chatID, localize(myConfig.Lang, "placeholder"))
 	if err != nil {
-		log.Printf("Error summarizing messages: %v", err)
-		return
+		log.Printf("Error sending placeholder message: %v", err)
+	}
+
+	// Describe the photos and links of the range while the placeholder is shown.
+	var extras enrichments
+	if myConfig.DescribeImages {
+		extras.photos = b.describePhotos(ctx, messages, excluded, myConfig)
+	}
+	if myConfig.LinkPreviews {
+		extras.links = previewLinks(ctx, messages, excluded, myConfig)
+	}
+	if len(extras.photos) > 0 || len(extras.links) > 0 {
+		concatenatedText = strings.TrimSpace(formatMessages(messages, excluded, extras))
+	}
+
+	// Strip personal data before the transcript leaves for the provider.
+	redactedText, restore := redactTranscript(concatenatedText, messages, myConfig.Redact)
+
+	var onProgress func(string)
+	if live != nil {
+		onProgress = func(text string) { live.Update(restore(text)) }
+	}
+
+	lines := strings.Split(redactedText, "\n")
+	summary, provider, err := chain.SummarizeLong(ctx, lines, myConfig.Lang, myConfig.Style, llm.InputBudget(myConfig.LLMContextTokens), onProgress)
+	if err != nil {
+		if live != nil {
+			live.Delete(ctx)
+		}
+		return fmt.Errorf("summarizing messages: %w", err)
 	}
 
-	sendSummary(update.Message.Chat.ID, summary)
+	summary = restore(summary)
+	if truncated {
+		summary = localize(myConfig.Lang, "truncated", db.MaxMessages) + "\n\n" + summary
+	}
+	if opts.title != "" {
+		summary = opts.title + "\n\n" + summary
+	}
+	if live != nil {
+		return live.Finish(ctx, withProviderNote(summary, provider))
+	}
+	return b.sendSummary(ctx, chatID, withProviderNote(summary, provider))
 }
 
-func formatMessages(messages []db.Message) string {
+// enrichments holds text generated for a transcript, by message ID.
+type enrichments struct {
+	// photos holds photo descriptions.
+	photos map[int64]string
+	// links holds previews of the pages linked by a message.
+	links map[int64][]string
+}
+
+// formatMessages renders one line per message, skipping the authors in excluded.
+// Media, forwards and replies are marked so the summarizer knows what it cannot see,
+// followed by the photo descriptions and link previews in extras:
+//
+//	Alice (↪ replying to Bob): [photo: a desk by a window] the new office
+//	Carol: [forwarded from Dave] meeting moved to 3pm
+//	Erin: read this https://example.com/a [link: Title — description]
+func formatMessages(messages []db.Message, excluded map[int64]bool, extras enrichments) string {
+	senders := map[int64]string{}
 	var sb strings.Builder
 	for _, msg := range messages {
-		sender := msg.Username
-		switch {
-		case msg.Name != "" && msg.LastName != "":
-			sender = fmt.Sprintf("%s %s", msg.Name, msg.LastName)
-		case msg.Name != "":
-			sender = msg.Name
-		case msg.LastName != "":
-			sender = msg.LastName
+		if excluded[msg.UserID] {
+			continue
+		}
+		sender := senderName(msg)
+		senders[msg.MessageID] = sender
+
+		if msg.ReplyToMessageID != 0 {
+			if author, ok := senders[msg.ReplyToMessageID]; ok {
+				sender = fmt.Sprintf("%s (↪ replying to %s)", sender, author)
+			} else {
+				sender = fmt.Sprintf("%s (↪ replying to an earlier message)", sender)
+			}
+		}
+		content := messageText(msg, extras.photos[msg.MessageID])
+		for _, preview := range extras.links[msg.MessageID] {
+			content += fmt.Sprintf(" [link: %s]", preview)
 		}
-		sb.WriteString(fmt.Sprintf("%s: %s\n", sender, msg.Content))
+		content = strings.ReplaceAll(content, "\n", " ")
+		sb.WriteString(fmt.Sprintf("%s: %s\n", sender, content))
 	}
 	return sb.String()
 }
 
-func sendSummary(chatID int64, summary string) {
-	bot, err := NewBot()
-	if err != nil {
-		log.Printf("Error creating bot instance: %v", err)
-		return
+// messageText renders the content of a message with its type, forward origin and
+// the description of its media, if any.
+func messageText(msg db.Message, description string) string {
+	var parts []string
+	if msg.ForwardFrom != "" {
+		parts = append(parts, fmt.Sprintf("[forwarded from %s]", msg.ForwardFrom))
+	}
+	if msg.Type != "" && msg.Type != db.MessageText {
+		marker := strings.ReplaceAll(msg.Type, "_", " ")
+		if description != "" {
+			marker += ": " + description
+		}
+		parts = append(parts, "["+marker+"]")
+	}
+	for _, text := range []string{msg.Content, msg.Caption, msg.Transcript} {
+		if text != "" {
+			parts = append(parts, text)
+		}
 	}
+	return strings.Join(parts, " ")
+}
 
-	msg := tgbotapi.NewMessage(chatID, summary)
-	_, err = bot.api.Send(msg)
-	if err != nil {
-		log.Printf("Error sending summary: %v", err)
+// senderName returns the full name of the author of a message, or their username.
+func senderName(msg db.Message) string {
+	switch {
+	case msg.Name != "" && msg.LastName != "":
+		return fmt.Sprintf("%s %s", msg.Name, msg.LastName)
+	case msg.Name != "":
+		return msg.Name
+	case msg.LastName != "":
+		return msg.LastName
+	}
+	return msg.Username
+}
+
+// redactTranscript applies the redaction mode of the group to the transcript. The
+// returned function maps pseudonyms in the summary back to the real names.
+func redactTranscript(text string, messages []db.Message, mode string) (string, func(string) string) {
+	restore := func(summary string) string { return summary }
+	if mode == redact.Off || mode == "" {
+		return text, restore
+	}
+
+	text = redact.PIIText(text)
+	if mode == redact.All {
+		pseudonyms := redact.NewPseudonyms()
+		for _, msg := range messages {
+			var usernames []string
+			if msg.Username != "" {
+				usernames = []string{"@" + msg.Username, msg.Username}
+			}
+			pseudonyms.Add(senderName(msg), append(usernames, msg.Name)...)
+			if msg.ForwardFrom != "" {
+				pseudonyms.Add(msg.ForwardFrom)
+			}
+		}
+		text = pseudonyms.Apply(text)
+		restore = pseudonyms.Restore
+	}
+	return text, restore
+}
+
+// withProviderNote appends the name of the backend that produced the summary.
+func withProviderNote(summary, provider string) string {
+	return fmt.Sprintf("%s\n\n— %s", strings.TrimSpace(summary), provider)
+}
+
+// sendSummary posts a summary, split over several messages when it is too long for one.
+func (b *Bot) sendSummary(ctx context.Context, chatID int64, summary string) error {
+	for _, part := range splitMessage(summary) {
+		if _, err := b.app.Sender.Send(ctx, chatID, tgbotapi.NewMessage(chatID, part)); err != nil {
+			return fmt.Errorf("sending summary: %w", err)
+		}
+	}
+	return nil
+}
+
+// replyText answers a message with plain text.
+func (b *Bot) replyText(ctx context.Context, message *tgbotapi.Message, text string) {
+	reply := tgbotapi.NewMessage(message.Chat.ID, text)
+	reply.ReplyToMessageID = message.MessageID
+	if _, err := b.app.Sender.Send(ctx, message.Chat.ID, reply); err != nil {
+		log.Printf("Error sending reply: %v", err)
 	}
 }
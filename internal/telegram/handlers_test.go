@@ -0,0 +1,315 @@
+package telegram
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tldr-telegram-bot/internal/db"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// openTestStore returns a migrated SQLite store in a temporary directory.
+func openTestStore(t *testing.T) db.MessageStore {
+	t.Helper()
+	store, err := db.Open("sqlite://"+filepath.Join(t.TempDir(), "bot.db"), nil)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if _, err := store.MigrateUp(context.Background()); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	return store
+}
+
+func TestSkipLoggingOptedOutUsers(t *testing.T) {
+	ctx := context.Background()
+	store := openTestStore(t)
+	if _, err := store.OptOut(ctx, 1); err != nil {
+		t.Fatalf("OptOut: %v", err)
+	}
+
+	chat := &tgbotapi.Chat{ID: -100, Type: "supergroup"}
+	for name, tc := range map[string]struct {
+		from *tgbotapi.User
+		want bool
+	}{
+		"opted out":    {&tgbotapi.User{ID: 1}, true},
+		"other member": {&tgbotapi.User{ID: 2}, false},
+		"no author":    {nil, true},
+	} {
+		message := &tgbotapi.Message{MessageID: 1, From: tc.from, Chat: chat, Text: "hello"}
+		if got := skipLogging(ctx, store, message); got != tc.want {
+			t.Errorf("%s: skipLogging = %v, want %v", name, got, tc.want)
+		}
+	}
+
+	// Opting back in lets new messages be stored again.
+	if err := store.OptIn(ctx, 1); err != nil {
+		t.Fatalf("OptIn: %v", err)
+	}
+	if skipLogging(ctx, store, &tgbotapi.Message{From: &tgbotapi.User{ID: 1}, Chat: chat}) {
+		t.Error("skipLogging after OptIn = true, want false")
+	}
+}
+
+func TestFormatMessagesDropsExcludedAuthors(t *testing.T) {
+	at := time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC)
+	messages := []db.Message{
+		{MessageID: 1, Timestamp: at, UserID: 1, Name: "Alice", Content: "hello", Type: db.MessageText},
+		{MessageID: 2, Timestamp: at, UserID: 2, Name: "Bob", Content: "my secret", Type: db.MessageText},
+		{MessageID: 3, Timestamp: at, UserID: 1, Name: "Alice", Content: "bye", Type: db.MessageText, ReplyToMessageID: 2},
+	}
+	extras := enrichments{
+		photos: map[int64]string{2: "a private photo"},
+		links:  map[int64][]string{2: {"Private page"}},
+	}
+
+	got := formatMessages(messages, map[int64]bool{2: true}, extras)
+	want := "Alice: hello\nAlice (↪ replying to an earlier message): bye\n"
+	if got != want {
+		t.Errorf("formatMessages = %q, want %q", got, want)
+	}
+	for _, leaked := range []string{"Bob", "secret", "private"} {
+		if strings.Contains(got, leaked) {
+			t.Errorf("transcript mentions %q of an excluded author", leaked)
+		}
+	}
+}
+
+// telegramMessage returns a message sent by Alice to a test group.
+func telegramMessage(id int, text string) *tgbotapi.Message {
+	return &tgbotapi.Message{
+		MessageID: id,
+		From:      &tgbotapi.User{ID: 1, FirstName: "Alice"},
+		Chat:      &tgbotapi.Chat{ID: -100, Type: "supergroup"},
+		Date:      int(time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC).Unix()),
+		Text:      text,
+	}
+}
+
+func TestParseMessage(t *testing.T) {
+	for name, tc := range map[string]struct {
+		edit               func(m *tgbotapi.Message)
+		wantType, wantID   string
+		wantContent        string
+		wantForward        string
+		wantReplyToID      int64
+		wantName, wantUser string
+	}{
+		"text": {
+			edit:     func(m *tgbotapi.Message) { m.Text = "hello" },
+			wantType: db.MessageText, wantContent: "hello", wantName: "Alice",
+		},
+		"largest photo size": {
+			edit: func(m *tgbotapi.Message) {
+				m.Photo = []tgbotapi.PhotoSize{{FileID: "small"}, {FileID: "large"}}
+			},
+			wantType: db.MessagePhoto, wantID: "large", wantName: "Alice",
+		},
+		"animation before document": {
+			edit: func(m *tgbotapi.Message) {
+				m.Animation = &tgbotapi.Animation{FileID: "gif"}
+				m.Document = &tgbotapi.Document{FileID: "gif", FileName: "cat.mp4"}
+			},
+			wantType: db.MessageAnimation, wantID: "gif", wantName: "Alice",
+		},
+		"document": {
+			edit:     func(m *tgbotapi.Message) { m.Document = &tgbotapi.Document{FileID: "doc", FileName: "report.pdf"} },
+			wantType: db.MessageDocument, wantID: "doc", wantContent: "report.pdf", wantName: "Alice",
+		},
+		"venue before location": {
+			edit: func(m *tgbotapi.Message) {
+				m.Location = &tgbotapi.Location{Latitude: 52.52, Longitude: 13.405}
+				m.Venue = &tgbotapi.Venue{Location: *m.Location, Title: "Cafe", Address: "Main St 1"}
+			},
+			wantType: db.MessageLocation, wantContent: "Cafe, Main St 1", wantName: "Alice",
+		},
+		"location": {
+			edit:     func(m *tgbotapi.Message) { m.Location = &tgbotapi.Location{Latitude: 52.52, Longitude: 13.405} },
+			wantType: db.MessageLocation, wantContent: "52.52000, 13.40500", wantName: "Alice",
+		},
+		"poll": {
+			edit: func(m *tgbotapi.Message) {
+				m.Poll = &tgbotapi.Poll{Question: "Lunch?", Options: []tgbotapi.PollOption{{Text: "Pizza"}, {Text: "Sushi"}}}
+			},
+			wantType: db.MessagePoll, wantContent: "Lunch? (Pizza / Sushi)", wantName: "Alice",
+		},
+		"reply": {
+			edit:     func(m *tgbotapi.Message) { m.ReplyToMessage = telegramMessage(7, "question") },
+			wantType: db.MessageText, wantReplyToID: 7, wantName: "Alice",
+		},
+		"forwarded from a user": {
+			edit:     func(m *tgbotapi.Message) { m.ForwardFrom = &tgbotapi.User{FirstName: "Carol", LastName: "Jones"} },
+			wantType: db.MessageText, wantForward: "Carol Jones", wantName: "Alice",
+		},
+		"forwarded from a channel": {
+			edit:     func(m *tgbotapi.Message) { m.ForwardFromChat = &tgbotapi.Chat{Title: "News"} },
+			wantType: db.MessageText, wantForward: "News", wantName: "Alice",
+		},
+		"forwarded from a hidden user": {
+			edit:     func(m *tgbotapi.Message) { m.ForwardSenderName = "Dave" },
+			wantType: db.MessageText, wantForward: "Dave", wantName: "Alice",
+		},
+		"username": {
+			edit:     func(m *tgbotapi.Message) { m.From = &tgbotapi.User{ID: 1, UserName: "alice"} },
+			wantType: db.MessageText, wantUser: "alice",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			message := telegramMessage(1, "")
+			tc.edit(message)
+			got := parseMessage(message)
+			if got.Type != tc.wantType || got.FileID != tc.wantID || got.Content != tc.wantContent {
+				t.Errorf("type, file ID, content = %q, %q, %q, want %q, %q, %q",
+					got.Type, got.FileID, got.Content, tc.wantType, tc.wantID, tc.wantContent)
+			}
+			if got.ForwardFrom != tc.wantForward || got.ReplyToMessageID != tc.wantReplyToID {
+				t.Errorf("forward, reply = %q, %d, want %q, %d", got.ForwardFrom, got.ReplyToMessageID, tc.wantForward, tc.wantReplyToID)
+			}
+			if got.Name != tc.wantName || got.Username != tc.wantUser || got.UserID != 1 || got.GroupID != -100 {
+				t.Errorf("author = %+v, want %q (@%q) of user 1 in group -100", got, tc.wantName, tc.wantUser)
+			}
+		})
+	}
+}
+
+func TestFormatMessages(t *testing.T) {
+	for name, tc := range map[string]struct {
+		messages []*tgbotapi.Message
+		extras   enrichments
+		want     string
+	}{
+		"text": {
+			messages: []*tgbotapi.Message{telegramMessage(1, "hello")},
+			want:     "Alice: hello\n",
+		},
+		"full name and newlines": {
+			messages: []*tgbotapi.Message{func() *tgbotapi.Message {
+				m := telegramMessage(1, "first line\nsecond line")
+				m.From.LastName = "Smith"
+				return m
+			}()},
+			want: "Alice Smith: first line second line\n",
+		},
+		"photo with a description and a caption": {
+			messages: []*tgbotapi.Message{func() *tgbotapi.Message {
+				m := telegramMessage(1, "")
+				m.Photo, m.Caption = []tgbotapi.PhotoSize{{FileID: "large"}}, "look"
+				return m
+			}()},
+			extras: enrichments{photos: map[int64]string{1: "a cat on a sofa"}},
+			want:   "Alice: [photo: a cat on a sofa] look\n",
+		},
+		"photo without a description": {
+			messages: []*tgbotapi.Message{func() *tgbotapi.Message {
+				m := telegramMessage(1, "")
+				m.Photo = []tgbotapi.PhotoSize{{FileID: "large"}}
+				return m
+			}()},
+			want: "Alice: [photo]\n",
+		},
+		"replies": {
+			messages: []*tgbotapi.Message{
+				telegramMessage(1, "lunch?"),
+				func() *tgbotapi.Message {
+					m := telegramMessage(2, "yes")
+					m.From = &tgbotapi.User{ID: 2, FirstName: "Bob"}
+					m.ReplyToMessage = telegramMessage(1, "lunch?")
+					return m
+				}(),
+				func() *tgbotapi.Message {
+					m := telegramMessage(3, "as I said")
+					m.ReplyToMessage = telegramMessage(99, "")
+					return m
+				}(),
+			},
+			want: "Alice: lunch?\nBob (↪ replying to Alice): yes\nAlice (↪ replying to an earlier message): as I said\n",
+		},
+		"forwarded": {
+			messages: []*tgbotapi.Message{func() *tgbotapi.Message {
+				m := telegramMessage(1, "big news")
+				m.ForwardFromChat = &tgbotapi.Chat{Title: "News"}
+				return m
+			}()},
+			want: "Alice: [forwarded from News] big news\n",
+		},
+		"poll": {
+			messages: []*tgbotapi.Message{func() *tgbotapi.Message {
+				m := telegramMessage(1, "")
+				m.Poll = &tgbotapi.Poll{Question: "Lunch?", Options: []tgbotapi.PollOption{{Text: "Pizza"}, {Text: "Sushi"}}}
+				return m
+			}()},
+			want: "Alice: [poll] Lunch? (Pizza / Sushi)\n",
+		},
+		"venue": {
+			messages: []*tgbotapi.Message{func() *tgbotapi.Message {
+				m := telegramMessage(1, "")
+				m.Location = &tgbotapi.Location{Latitude: 52.52, Longitude: 13.405}
+				m.Venue = &tgbotapi.Venue{Location: *m.Location, Title: "Cafe", Address: "Main St 1"}
+				return m
+			}()},
+			want: "Alice: [location] Cafe, Main St 1\n",
+		},
+		"location": {
+			messages: []*tgbotapi.Message{func() *tgbotapi.Message {
+				m := telegramMessage(1, "")
+				m.Location = &tgbotapi.Location{Latitude: 52.52, Longitude: 13.405}
+				return m
+			}()},
+			want: "Alice: [location] 52.52000, 13.40500\n",
+		},
+		"animation sent as a document": {
+			messages: []*tgbotapi.Message{func() *tgbotapi.Message {
+				m := telegramMessage(1, "")
+				m.Animation = &tgbotapi.Animation{FileID: "gif"}
+				m.Document = &tgbotapi.Document{FileID: "gif", FileName: "cat.mp4"}
+				m.Caption = "so true"
+				return m
+			}()},
+			want: "Alice: [animation] so true\n",
+		},
+		"document": {
+			messages: []*tgbotapi.Message{func() *tgbotapi.Message {
+				m := telegramMessage(1, "")
+				m.Document = &tgbotapi.Document{FileID: "doc", FileName: "report.pdf"}
+				return m
+			}()},
+			want: "Alice: [document] report.pdf\n",
+		},
+		"video note": {
+			messages: []*tgbotapi.Message{func() *tgbotapi.Message {
+				m := telegramMessage(1, "")
+				m.VideoNote = &tgbotapi.VideoNote{FileID: "note"}
+				return m
+			}()},
+			want: "Alice: [video note]\n",
+		},
+		"link previews": {
+			messages: []*tgbotapi.Message{telegramMessage(1, "see https://example.com")},
+			extras:   enrichments{links: map[int64][]string{1: {"Example Domain"}}},
+			want:     "Alice: see https://example.com [link: Example Domain]\n",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			messages := make([]db.Message, len(tc.messages))
+			for i, message := range tc.messages {
+				messages[i] = parseMessage(message)
+			}
+			if got := formatMessages(messages, nil, tc.extras); got != tc.want {
+				t.Errorf("formatMessages = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMessageTextIncludesTranscripts(t *testing.T) {
+	voice := db.Message{Type: db.MessageVoice, Transcript: "spoken words"}
+	if got, want := messageText(voice, ""), "[voice] spoken words"; got != want {
+		t.Errorf("messageText = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,85 @@
+package telegram
+
+import "fmt"
+
+// texts holds the user-facing strings of the bot, keyed by language and message key.
+var texts = map[string]map[string]string{
+	"en": {
+		"placeholder":      "⏳ Summarizing the conversation…",
+		"no_messages":      "No messages found to summarize.",
+		"truncated":        "⚠️ Only the last %d messages of this range were summarized.",
+		"tldr_usage":       "Usage: /tldr [2h | 200 | since 09:00 | today], or reply /tldr to a message.\n%s",
+		"range_started":    "Start marked. Reply /tldr end to the last message of the range, or send /tldr end to summarize up to now. The mark is kept for 24 hours and is lost if the bot restarts.",
+		"range_no_start":   "No start marked. Reply /tldr start to the first message of the range first.",
+		"range_need_reply": "Reply /tldr start to the first message of the range.",
+		"range_bad_link":   "Could not use that link: %s",
+		"admin_only":       "Only group administrators can do that.",
+		"digest_title":     "📅 Digest %s – %s",
+		"digest_off":       "Automatic digest is off. Enable it with /digest daily 18:00 or /digest weekly mon 09:00, optionally followed by a timezone.",
+		"digest_status":    "Automatic digest: %s. Next run: %s.",
+		"digest_usage":     "Usage: /digest [off | daily HH:MM [timezone] | weekly <weekday> HH:MM [timezone]]\n%s",
+		"settings_status":  "Settings\nlang: %s\nprovider: %s\nwindow: %s\nstyle: %s\nretention: %s\nredact: %s\nimages: %s\ntimezone: %s",
+		"settings_usage":   "Usage: /settings [lang <pt|en|es> | provider <name> | window <2h> | style <brief|bullets|detailed> | retention <30d|forever> | redact <off|pii|all> | images <on|off> | timezone <Area/City> | <key> default | reset]\n%s",
+		"forget_done":      "Deleted %d stored message(s).",
+		"forget_usage":     "Usage: reply /forget to a message, or /forget [all | 2h]\n%s",
+		"optout_done":      "Your messages will no longer be stored or summarized. Deleted %d stored message(s). Send /optin to undo.",
+		"optin_done":       "Your new messages will be stored and summarized again.",
+		"optout_anonymous": "Opting out applies to a person, so it cannot be done while posting anonymously or as a channel. Send the command from your own account.",
+	},
+	"pt": {
+		"placeholder":      "⏳ Resumindo a conversa…",
+		"no_messages":      "Nenhuma mensagem encontrada para resumir.",
+		"truncated":        "⚠️ Apenas as últimas %d mensagens deste intervalo foram resumidas.",
+		"tldr_usage":       "Uso: /tldr [2h | 200 | since 09:00 | today], ou responda /tldr a uma mensagem.\n%s",
+		"range_started":    "Início marcado. Responda /tldr end à última mensagem do intervalo, ou envie /tldr end para resumir até agora. A marca vale por 24 horas e se perde se o bot reiniciar.",
+		"range_no_start":   "Nenhum início marcado. Responda /tldr start à primeira mensagem do intervalo antes.",
+		"range_need_reply": "Responda /tldr start à primeira mensagem do intervalo.",
+		"range_bad_link":   "Não foi possível usar esse link: %s",
+		"admin_only":       "Apenas administradores do grupo podem fazer isso.",
+		"digest_title":     "📅 Resumo %s – %s",
+		"digest_off":       "O resumo automático está desligado. Ative com /digest daily 18:00 ou /digest weekly mon 09:00, opcionalmente seguido de um fuso horário.",
+		"digest_status":    "Resumo automático: %s. Próxima execução: %s.",
+		"digest_usage":     "Uso: /digest [off | daily HH:MM [fuso] | weekly <dia> HH:MM [fuso]]\n%s",
+		"settings_status":  "Configurações\nidioma: %s\nprovedor: %s\njanela: %s\nestilo: %s\nretenção: %s\nredação: %s\nimagens: %s\nfuso horário: %s",
+		"settings_usage":   "Uso: /settings [lang <pt|en|es> | provider <nome> | window <2h> | style <brief|bullets|detailed> | retention <30d|forever> | redact <off|pii|all> | images <on|off> | timezone <Área/Cidade> | <chave> default | reset]\n%s",
+		"forget_done":      "%d mensagem(ns) armazenada(s) apagada(s).",
+		"forget_usage":     "Uso: responda /forget a uma mensagem, ou /forget [all | 2h]\n%s",
+		"optout_done":      "Suas mensagens não serão mais armazenadas nem resumidas. %d mensagem(ns) armazenada(s) apagada(s). Envie /optin para desfazer.",
+		"optin_done":       "Suas novas mensagens voltarão a ser armazenadas e resumidas.",
+		"optout_anonymous": "A exclusão vale para uma pessoa, então não pode ser feita enviando anonimamente ou como canal. Envie o comando pela sua própria conta.",
+	},
+	"es": {
+		"placeholder":      "⏳ Resumiendo la conversación…",
+		"no_messages":      "No se encontraron mensajes para resumir.",
+		"truncated":        "⚠️ Solo se resumieron los últimos %d mensajes de este rango.",
+		"tldr_usage":       "Uso: /tldr [2h | 200 | since 09:00 | today], o responde /tldr a un mensaje.\n%s",
+		"range_started":    "Inicio marcado. Responde /tldr end al último mensaje del rango, o envía /tldr end para resumir hasta ahora. La marca dura 24 horas y se pierde si el bot se reinicia.",
+		"range_no_start":   "No hay inicio marcado. Responde /tldr start al primer mensaje del rango primero.",
+		"range_need_reply": "Responde /tldr start al primer mensaje del rango.",
+		"range_bad_link":   "No se pudo usar ese enlace: %s",
+		"admin_only":       "Solo los administradores del grupo pueden hacer eso.",
+		"digest_title":     "📅 Resumen %s – %s",
+		"digest_off":       "El resumen automático está desactivado. Actívalo con /digest daily 18:00 o /digest weekly mon 09:00, opcionalmente seguido de una zona horaria.",
+		"digest_status":    "Resumen automático: %s. Próxima ejecución: %s.",
+		"digest_usage":     "Uso: /digest [off | daily HH:MM [zona] | weekly <día> HH:MM [zona]]\n%s",
+		"settings_status":  "Configuración\nidioma: %s\nproveedor: %s\nventana: %s\nestilo: %s\nretención: %s\nredacción: %s\nimágenes: %s\nzona horaria: %s",
+		"settings_usage":   "Uso: /settings [lang <pt|en|es> | provider <nombre> | window <2h> | style <brief|bullets|detailed> | retention <30d|forever> | redact <off|pii|all> | images <on|off> | timezone <Área/Ciudad> | <clave> default | reset]\n%s",
+		"forget_done":      "Se borraron %d mensaje(s) almacenado(s).",
+		"forget_usage":     "Uso: responde /forget a un mensaje, o /forget [all | 2h]\n%s",
+		"optout_done":      "Tus mensajes ya no se almacenarán ni se resumirán. Se borraron %d mensaje(s) almacenado(s). Envía /optin para deshacerlo.",
+		"optin_done":       "Tus nuevos mensajes volverán a almacenarse y resumirse.",
+		"optout_anonymous": "La exclusión se aplica a una persona, así que no puede hacerse enviando de forma anónima o como canal. Envía el comando desde tu propia cuenta.",
+	},
+}
+
+// localize returns the text for key in lang, falling back to English, formatted with args.
+func localize(lang, key string, args ...interface{}) string {
+	text, ok := texts[lang][key]
+	if !ok {
+		text = texts["en"][key]
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(text, args...)
+	}
+	return text
+}
@@ -0,0 +1,97 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+
+	"tldr-telegram-bot/internal/config"
+	"tldr-telegram-bot/internal/db"
+	"tldr-telegram-bot/internal/llm"
+)
+
+// maxImageSize bounds the photos downloaded for description.
+const maxImageSize = 10 << 20
+
+// maxConcurrentPhotos bounds how many photos are described at once.
+const maxConcurrentPhotos = 3
+
+// describePhotos describes the most recent photos among messages, at most
+// myConfig.MaxImages of them, with the first multimodal provider of the chain. The
+// photos are described concurrently, all within one myConfig.LLMTimeout, so that they
+// cannot hold up the summary for long. It returns the descriptions by message ID;
+// photos that fail or run out of time are left out.
+func (b *Bot) describePhotos(ctx context.Context, messages []db.Message, excluded map[int64]bool, myConfig *config.Config) map[int64]string {
+	describer, provider, err := llm.NewImageDescriber(myConfig.LLMChain())
+	if err != nil {
+		log.Printf("Skipping photo descriptions: %v", err)
+		return nil
+	}
+	return describeRecentPhotos(ctx, messages, excluded, myConfig, func(ctx context.Context, msg db.Message) (string, error) {
+		description, err := b.describePhoto(ctx, describer, msg.FileID, myConfig)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", provider, err)
+		}
+		return description, nil
+	})
+}
+
+// describeRecentPhotos runs describe for the photos picked by describePhotos, under
+// its deadline, and collects the descriptions that succeed.
+func describeRecentPhotos(ctx context.Context, messages []db.Message, excluded map[int64]bool, myConfig *config.Config, describe func(context.Context, db.Message) (string, error)) map[int64]string {
+	var photos []db.Message
+	for i := len(messages) - 1; i >= 0 && len(photos) < myConfig.MaxImages; i-- {
+		msg := messages[i]
+		if msg.Type == db.MessagePhoto && msg.FileID != "" && !excluded[msg.UserID] {
+			photos = append(photos, msg)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, myConfig.LLMTimeout)
+	defer cancel()
+
+	var (
+		mu           sync.Mutex
+		wg           sync.WaitGroup
+		slots        = make(chan struct{}, maxConcurrentPhotos)
+		descriptions = map[int64]string{}
+	)
+	for _, msg := range photos {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(msg db.Message) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			description, err := describe(ctx, msg)
+			if err != nil {
+				log.Printf("Error describing photo %d: %v", msg.MessageID, err)
+				return
+			}
+			mu.Lock()
+			descriptions[msg.MessageID] = description
+			mu.Unlock()
+		}(msg)
+	}
+	wg.Wait()
+	return descriptions
+}
+
+// describePhoto downloads one photo and asks the provider to describe it.
+func (b *Bot) describePhoto(ctx context.Context, describer llm.ImageDescriber, fileID string, myConfig *config.Config) (string, error) {
+	file, _, err := b.downloadFile(ctx, fileID)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	image, err := io.ReadAll(io.LimitReader(file, maxImageSize))
+	if err != nil {
+		return "", err
+	}
+	return describer.DescribeImage(ctx, image, http.DetectContentType(image), myConfig.Lang)
+}
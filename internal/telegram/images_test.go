@@ -0,0 +1,55 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"tldr-telegram-bot/internal/config"
+	"tldr-telegram-bot/internal/db"
+)
+
+func TestDescribeRecentPhotosKeepsWhatSucceeds(t *testing.T) {
+	photo := func(id, userID int64) db.Message {
+		return db.Message{MessageID: id, UserID: userID, Type: db.MessagePhoto, FileID: fmt.Sprintf("file%d", id)}
+	}
+	messages := []db.Message{
+		photo(1, 1), // beyond MaxImages
+		photo(2, 1),
+		photo(3, 1), // fails
+		{MessageID: 4, UserID: 1, Type: db.MessageText, Content: "nice"},
+		photo(5, 2), // excluded author
+		photo(6, 1), // runs out of time
+		photo(7, 1),
+	}
+	myConfig := &config.Config{MaxImages: 4, LLMTimeout: 100 * time.Millisecond}
+
+	var mu sync.Mutex
+	var asked []int64
+	got := describeRecentPhotos(context.Background(), messages, map[int64]bool{2: true}, myConfig,
+		func(ctx context.Context, msg db.Message) (string, error) {
+			mu.Lock()
+			asked = append(asked, msg.MessageID)
+			mu.Unlock()
+			switch msg.MessageID {
+			case 3:
+				return "", errors.New("model overloaded")
+			case 6:
+				<-ctx.Done()
+				return "", ctx.Err()
+			}
+			return "photo " + msg.FileID, nil
+		})
+
+	want := map[int64]string{2: "photo file2", 7: "photo file7"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("descriptions = %q, want %q", got, want)
+	}
+	if len(asked) != 4 {
+		t.Errorf("described photos %v, want the 4 most recent of included authors", asked)
+	}
+}
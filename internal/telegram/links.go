@@ -0,0 +1,78 @@
+package telegram
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"tldr-telegram-bot/internal/config"
+	"tldr-telegram-bot/internal/db"
+	"tldr-telegram-bot/internal/linkpreview"
+	"tldr-telegram-bot/internal/redact"
+)
+
+// maxConcurrentLinks bounds how many pages are fetched at once.
+const maxConcurrentLinks = 4
+
+// previewLinks fetches the pages linked in messages, at most myConfig.MaxLinks of them
+// starting with the most recent, and returns their previews by message ID. The pages
+// are fetched concurrently, all within one LinkPreviewTimeout; pages that cannot be
+// fetched by then are left out, and so are URLs carrying credentials, which the bot
+// must not use on anyone's behalf.
+func previewLinks(ctx context.Context, messages []db.Message, excluded map[int64]bool, myConfig *config.Config) map[int64][]string {
+	type link struct {
+		messageID int64
+		url       string
+	}
+	var links []link
+	fetched := map[string]bool{}
+	for i := len(messages) - 1; i >= 0 && len(links) < myConfig.MaxLinks; i-- {
+		msg := messages[i]
+		if excluded[msg.UserID] {
+			continue
+		}
+		for _, url := range linkpreview.ExtractURLs(msg.Content + " " + msg.Caption) {
+			if fetched[url] || len(links) >= myConfig.MaxLinks || redact.HasCredentials(url) {
+				continue
+			}
+			fetched[url] = true
+			links = append(links, link{msg.MessageID, url})
+		}
+	}
+
+	fetcher := linkpreview.NewFetcher(myConfig.LinkPreviewTimeout, int64(myConfig.LinkPreviewMaxBytes), myConfig.LinkPreviewAllowPrivate)
+	ctx, cancel := context.WithTimeout(ctx, myConfig.LinkPreviewTimeout)
+	defer cancel()
+
+	var (
+		wg    sync.WaitGroup
+		slots = make(chan struct{}, maxConcurrentLinks)
+		// Each fetch fills its own entry, so the previews keep the order of the links.
+		results = make([]string, len(links))
+	)
+	for i, l := range links {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(i int, l link) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			preview, err := fetcher.Fetch(ctx, l.url)
+			if err != nil {
+				log.Printf("Error fetching link preview: %v", err)
+				return
+			}
+			results[i] = preview.String()
+		}(i, l)
+	}
+	wg.Wait()
+
+	previews := map[int64][]string{}
+	for i, l := range links {
+		if results[i] != "" {
+			previews[l.messageID] = append(previews[l.messageID], results[i])
+		}
+	}
+	return previews
+}
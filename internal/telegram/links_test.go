@@ -0,0 +1,50 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"tldr-telegram-bot/internal/config"
+	"tldr-telegram-bot/internal/db"
+)
+
+func TestPreviewLinksFetchesConcurrentlyUnderOneDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/hang" {
+			<-r.Context().Done()
+			return
+		}
+		// Fetched one after another, three pages would not fit in the deadline.
+		time.Sleep(400 * time.Millisecond)
+		fmt.Fprintf(w, "<html><head><title>Page %s</title></head></html>", r.URL.Path[1:])
+	}))
+	t.Cleanup(server.Close)
+
+	messages := []db.Message{
+		{MessageID: 1, UserID: 1, Content: fmt.Sprintf("see %[1]s/1 and %[1]s/2", server.URL)},
+		{MessageID: 2, UserID: 1, Content: server.URL + "/hang", Caption: server.URL + "/3"},
+		{MessageID: 3, UserID: 2, Content: server.URL + "/4"},
+		{MessageID: 4, UserID: 1, Content: server.URL + "/1?token=secret"},
+	}
+	myConfig := &config.Config{
+		MaxLinks:                5,
+		LinkPreviewTimeout:      time.Second,
+		LinkPreviewMaxBytes:     1 << 20,
+		LinkPreviewAllowPrivate: true,
+	}
+
+	start := time.Now()
+	got := previewLinks(context.Background(), messages, map[int64]bool{2: true}, myConfig)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("previewLinks took %s, want at most about one LinkPreviewTimeout", elapsed)
+	}
+	want := map[int64][]string{1: {"Page 1", "Page 2"}, 2: {"Page 3"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("previews = %q, want %q", got, want)
+	}
+}
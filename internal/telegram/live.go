@@ -0,0 +1,138 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"tldr-telegram-bot/internal/sender"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// maxMessageLength is Telegram's limit for the text of a single message.
+	maxMessageLength = 4096
+	// editInterval throttles live edits; Telegram allows roughly 20 messages per minute in a group.
+	editInterval = 3 * time.Second
+)
+
+// liveMessage is a placeholder message that is progressively edited while a summary streams in.
+type liveMessage struct {
+	bot       *Bot
+	chatID    int64
+	messageID int
+
+	mu       sync.Mutex
+	lastText string
+	lastEdit time.Time
+}
+
+// newLiveMessage posts the placeholder text and returns a handle to edit it.
+func newLiveMessage(ctx context.Context, bot *Bot, chatID int64, placeholder string) (*liveMessage, error) {
+	sent, err := bot.app.Sender.Send(ctx, chatID, tgbotapi.NewMessage(chatID, placeholder))
+	if err != nil {
+		return nil, err
+	}
+	return &liveMessage{
+		bot:       bot,
+		chatID:    chatID,
+		messageID: sent.MessageID,
+		lastText:  placeholder,
+		lastEdit:  time.Now(),
+	}, nil
+}
+
+// Update shows the partial text, skipping edits that come faster than editInterval.
+// It is called while the summary streams in, so it never waits: the edit is dropped
+// when the chat is busy or rate limited, and a later update or Finish catches up.
+func (m *liveMessage) Update(text string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	text = truncateMessage(strings.TrimSpace(text) + " …")
+	if time.Since(m.lastEdit) < editInterval || text == m.lastText {
+		return
+	}
+	_, err := m.bot.app.Sender.TrySend(m.chatID, tgbotapi.NewEditMessageText(m.chatID, m.messageID, text))
+	switch {
+	case errors.Is(err, sender.ErrBusy):
+	case err != nil:
+		log.Printf("Error editing message: %v", err)
+	default:
+		m.lastText = text
+		m.lastEdit = time.Now()
+	}
+}
+
+// Finish replaces the placeholder with the final text. Text beyond Telegram's
+// length limit is sent as follow-up messages. If the placeholder cannot be edited,
+// e.g. because it was deleted, the text is sent as a new message instead. It returns
+// an error if any part of the text could not be posted.
+func (m *liveMessage) Finish(ctx context.Context, text string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	parts := splitMessage(text)
+	if err := m.edit(ctx, parts[0]); err != nil {
+		log.Printf("Error editing message, sending the summary instead: %v", err)
+		if _, err := m.bot.app.Sender.Send(ctx, m.chatID, tgbotapi.NewMessage(m.chatID, parts[0])); err != nil {
+			return fmt.Errorf("sending summary: %w", err)
+		}
+		m.Delete(ctx)
+	}
+	for _, part := range parts[1:] {
+		if _, err := m.bot.app.Sender.Send(ctx, m.chatID, tgbotapi.NewMessage(m.chatID, part)); err != nil {
+			return fmt.Errorf("sending summary: %w", err)
+		}
+	}
+	return nil
+}
+
+// Delete removes the placeholder, e.g. when no summary could be produced.
+func (m *liveMessage) Delete(ctx context.Context) {
+	if err := m.bot.app.Sender.Request(ctx, m.chatID, tgbotapi.NewDeleteMessage(m.chatID, m.messageID)); err != nil {
+		log.Printf("Error deleting placeholder message: %v", err)
+	}
+}
+
+// edit must be called with m.mu held.
+func (m *liveMessage) edit(ctx context.Context, text string) error {
+	if text == "" || text == m.lastText {
+		return nil // Telegram rejects edits that do not change the text
+	}
+	if _, err := m.bot.app.Sender.Send(ctx, m.chatID, tgbotapi.NewEditMessageText(m.chatID, m.messageID, text)); err != nil {
+		return err
+	}
+	m.lastText = text
+	m.lastEdit = time.Now()
+	return nil
+}
+
+// truncateMessage cuts text to Telegram's message length limit.
+func truncateMessage(text string) string {
+	runes := []rune(text)
+	if len(runes) <= maxMessageLength {
+		return text
+	}
+	return string(runes[:maxMessageLength-1]) + "…"
+}
+
+// splitMessage splits text into chunks that fit in a Telegram message, preferring line breaks.
+func splitMessage(text string) []string {
+	var parts []string
+	runes := []rune(text)
+	for len(runes) > maxMessageLength {
+		cut := maxMessageLength
+		if i := strings.LastIndex(string(runes[:cut]), "\n"); i > 0 {
+			cut = len([]rune(string(runes[:cut])[:i]))
+		}
+		parts = append(parts, strings.TrimSpace(string(runes[:cut])))
+		runes = runes[cut:]
+	}
+	return append(parts, strings.TrimSpace(string(runes)))
+}
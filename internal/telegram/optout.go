@@ -0,0 +1,76 @@
+package telegram
+
+import (
+	"context"
+	"log"
+
+	"tldr-telegram-bot/internal/db"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleOptOutCommand stops storing and summarizing the sender's messages in every group,
+// and deletes what was already stored from them.
+func (b *Bot) handleOptOutCommand(ctx context.Context, message *tgbotapi.Message) {
+	myConfig, err := b.loadGroupConfig(ctx, message.Chat.ID)
+	if err != nil {
+		log.Printf("Error loading config: %v", err)
+		return
+	}
+
+	if isAnonymousSender(message) {
+		b.replyText(ctx, message, localize(myConfig.Lang, "optout_anonymous"))
+		return
+	}
+
+	deleted, err := b.app.Store.OptOut(ctx, message.From.ID)
+	if err != nil {
+		log.Printf("Error opting out user %d: %v", message.From.ID, err)
+		return
+	}
+
+	log.Printf("User %d opted out, deleted %d message(s)", message.From.ID, deleted)
+	b.replyText(ctx, message, localize(myConfig.Lang, "optout_done", deleted))
+}
+
+// handleOptInCommand lets the sender's new messages be stored and summarized again.
+func (b *Bot) handleOptInCommand(ctx context.Context, message *tgbotapi.Message) {
+	myConfig, err := b.loadGroupConfig(ctx, message.Chat.ID)
+	if err != nil {
+		log.Printf("Error loading config: %v", err)
+		return
+	}
+
+	if isAnonymousSender(message) {
+		b.replyText(ctx, message, localize(myConfig.Lang, "optout_anonymous"))
+		return
+	}
+
+	if err := b.app.Store.OptIn(ctx, message.From.ID); err != nil {
+		log.Printf("Error opting in user %d: %v", message.From.ID, err)
+		return
+	}
+
+	log.Printf("User %d opted in", message.From.ID)
+	b.replyText(ctx, message, localize(myConfig.Lang, "optin_done"))
+}
+
+// isAnonymousSender reports whether a message was sent on behalf of a chat, by an
+// anonymous admin or as a channel. Its From is then a placeholder user shared by
+// every such sender, such as GroupAnonymousBot, which must not be opted out.
+func isAnonymousSender(message *tgbotapi.Message) bool {
+	return message.SenderChat != nil
+}
+
+// excludedUsers returns the authors of messages who opted out.
+func (b *Bot) excludedUsers(ctx context.Context, messages []db.Message) (map[int64]bool, error) {
+	seen := map[int64]bool{}
+	var userIDs []int64
+	for _, msg := range messages {
+		if !seen[msg.UserID] {
+			seen[msg.UserID] = true
+			userIDs = append(userIDs, msg.UserID)
+		}
+	}
+	return b.app.Store.GetOptedOutUsers(ctx, userIDs)
+}
@@ -0,0 +1,119 @@
+package telegram
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rangeStartTTL is how long a /tldr start mark waits for its matching /tldr end.
+const rangeStartTTL = 24 * time.Hour
+
+type rangeKey struct {
+	chatID int64
+	userID int64
+}
+
+type rangeStart struct {
+	messageID int64
+	markedAt  time.Time
+}
+
+// rangeStarts remembers, per chat and user, the message marked with /tldr start.
+// Marks live only in this process: a restart forgets them, as the reply to
+// /tldr start and the README tell users.
+var rangeStarts = struct {
+	sync.Mutex
+	m map[rangeKey]rangeStart
+}{m: map[rangeKey]rangeStart{}}
+
+// markRangeStart records messageID as the start of the user's next range.
+func markRangeStart(chatID, userID, messageID int64) {
+	rangeStarts.Lock()
+	defer rangeStarts.Unlock()
+
+	now := time.Now()
+	for key, start := range rangeStarts.m {
+		if now.Sub(start.markedAt) > rangeStartTTL {
+			delete(rangeStarts.m, key)
+		}
+	}
+	rangeStarts.m[rangeKey{chatID, userID}] = rangeStart{messageID: messageID, markedAt: now}
+}
+
+// takeRangeStart returns and forgets the user's pending start mark.
+func takeRangeStart(chatID, userID int64) (int64, bool) {
+	rangeStarts.Lock()
+	defer rangeStarts.Unlock()
+
+	key := rangeKey{chatID, userID}
+	start, ok := rangeStarts.m[key]
+	delete(rangeStarts.m, key)
+	if !ok || time.Since(start.markedAt) > rangeStartTTL {
+		return 0, false
+	}
+	return start.messageID, true
+}
+
+// messageLinkPrefixes are the ways a message link may start: either Telegram host,
+// with or without a scheme.
+var messageLinkPrefixes = func() []string {
+	var prefixes []string
+	for _, scheme := range []string{"https://", "http://", ""} {
+		for _, host := range []string{"t.me", "telegram.me"} {
+			prefixes = append(prefixes, scheme+host+"/")
+		}
+	}
+	return prefixes
+}()
+
+// parseMessageLink extracts the message ID from a t.me link pointing into the given chat.
+// Both public (t.me/<username>/<id>) and private (t.me/c/<internal id>/<id>) links are accepted.
+func parseMessageLink(link string, chatID int64, chatUsername string) (int64, error) {
+	if !isMessageLink(link) {
+		return 0, fmt.Errorf("not a Telegram message link: %q", link)
+	}
+	if !strings.Contains(link, "://") {
+		link = "https://" + link
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return 0, fmt.Errorf("not a Telegram message link: %q", link)
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	var chatMatches bool
+	switch {
+	case len(parts) >= 3 && parts[0] == "c":
+		// Private links omit the -100 prefix of supergroup IDs.
+		chatMatches = "-100"+parts[1] == strconv.FormatInt(chatID, 10)
+		parts = parts[1:]
+	case len(parts) >= 2:
+		chatMatches = chatUsername != "" && strings.EqualFold(parts[0], chatUsername)
+	default:
+		return 0, fmt.Errorf("not a Telegram message link: %q", link)
+	}
+	if !chatMatches {
+		return 0, fmt.Errorf("link does not point to this chat: %q", link)
+	}
+
+	// Topic links look like t.me/c/<chat>/<topic>/<id>; the message ID is always last.
+	messageID, err := strconv.ParseInt(parts[len(parts)-1], 10, 64)
+	if err != nil || messageID <= 0 {
+		return 0, fmt.Errorf("invalid message ID in link: %q", link)
+	}
+	return messageID, nil
+}
+
+// isMessageLink reports whether an argument looks like a t.me link.
+func isMessageLink(arg string) bool {
+	for _, prefix := range messageLinkPrefixes {
+		if strings.HasPrefix(arg, prefix) {
+			return true
+		}
+	}
+	return false
+}
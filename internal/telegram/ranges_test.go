@@ -0,0 +1,66 @@
+package telegram
+
+import "testing"
+
+func TestParseMessageLink(t *testing.T) {
+	const chatID, username = -1001234567890, "gophers"
+	for _, tc := range []struct {
+		link    string
+		want    int64
+		wantErr bool
+	}{
+		{link: "https://t.me/gophers/42", want: 42},
+		{link: "http://t.me/gophers/42", want: 42},
+		{link: "https://telegram.me/gophers/42", want: 42},
+		{link: "http://telegram.me/gophers/42", want: 42},
+		{link: "t.me/gophers/42", want: 42},
+		{link: "telegram.me/Gophers/42", want: 42},
+		{link: "https://t.me/gophers/42?single", want: 42},
+		{link: "https://t.me/c/1234567890/42", want: 42},
+		{link: "https://t.me/c/1234567890/7/42", want: 42}, // message 42 of topic 7
+		{link: "https://t.me/gophers/7/42", want: 42},
+
+		{link: "https://t.me/rustaceans/42", wantErr: true},
+		{link: "https://t.me/c/987654321/42", wantErr: true},
+		{link: "https://t.me/gophers", wantErr: true},
+		{link: "https://t.me/gophers/abc", wantErr: true},
+		{link: "https://t.me/gophers/0", wantErr: true},
+		{link: "https://example.com/gophers/42", wantErr: true},
+		{link: "ftp://t.me/gophers/42", wantErr: true},
+	} {
+		t.Run(tc.link, func(t *testing.T) {
+			got, err := parseMessageLink(tc.link, chatID, username)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("message ID = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseMessageLinkWithoutUsername(t *testing.T) {
+	// A private group has no username, so only /c/ links can point into it.
+	if _, err := parseMessageLink("https://t.me/gophers/42", -1001234567890, ""); err == nil {
+		t.Error("public link accepted for a chat without a username")
+	}
+}
+
+func TestIsMessageLink(t *testing.T) {
+	for link, want := range map[string]bool{
+		"https://t.me/gophers/42":        true,
+		"http://t.me/gophers/42":         true,
+		"https://telegram.me/gophers/42": true,
+		"http://telegram.me/gophers/42":  true,
+		"t.me/gophers/42":                true,
+		"telegram.me/gophers/42":         true,
+		"2h":                             false,
+		"https://t.mex/gophers/42":       false,
+		"https://example.com/t.me/1":     false,
+	} {
+		if got := isMessageLink(link); got != want {
+			t.Errorf("isMessageLink(%q) = %v, want %v", link, got, want)
+		}
+	}
+}
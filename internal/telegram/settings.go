@@ -0,0 +1,225 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"tldr-telegram-bot/internal/config"
+	"tldr-telegram-bot/internal/db"
+	"tldr-telegram-bot/internal/llm"
+	"tldr-telegram-bot/internal/redact"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// loadGroupConfig returns the configuration of a group: the environment defaults
+// overridden by the settings stored for the group.
+func (b *Bot) loadGroupConfig(ctx context.Context, groupID int64) (*config.Config, error) {
+	// Copy the defaults, which every handler shares.
+	groupConfig := *b.app.Config
+	myConfig := &groupConfig
+
+	settings, err := b.app.Store.GetGroupSettings(ctx, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("loading settings of group %d: %w", groupID, err)
+	}
+
+	if settings.Lang != "" {
+		myConfig.Lang = settings.Lang
+	}
+	if settings.Provider != "" {
+		myConfig.LLMProvider = settings.Provider
+	}
+	if settings.Window > 0 {
+		myConfig.Window = settings.Window
+	}
+	if settings.Style != "" {
+		myConfig.Style = settings.Style
+	}
+	myConfig.RetentionDays = settings.Retention(myConfig.RetentionDays)
+	if settings.Redact != "" {
+		myConfig.Redact = settings.Redact
+	}
+	if settings.DescribeImages != nil {
+		myConfig.DescribeImages = *settings.DescribeImages
+	}
+	if settings.Timezone != "" {
+		myConfig.Timezone = settings.Timezone
+	}
+	return myConfig, nil
+}
+
+// handleSettingsCommand shows or changes the settings of a group. Supported forms:
+//
+//	/settings                   show the effective settings
+//	/settings lang <pt|en|es>   summary language
+//	/settings provider <name>   primary LLM provider
+//	/settings window <2h>       default summary window
+//	/settings style <style>     summary style
+//	/settings retention <30d>   how long messages are kept ("forever" to keep them)
+//	/settings redact <mode>     personal data removed before prompting (off, pii, all)
+//	/settings images <on|off>   describe photos with a multimodal provider
+//	/settings timezone <zone>   IANA timezone of /tldr times and digests
+//	/settings <key> default     drop one override
+//	/settings reset             drop every override
+func (b *Bot) handleSettingsCommand(ctx context.Context, message *tgbotapi.Message) {
+	myConfig, err := b.loadGroupConfig(ctx, message.Chat.ID)
+	if err != nil {
+		log.Printf("Error loading config: %v", err)
+		return
+	}
+
+	fields := strings.Fields(message.CommandArguments())
+	if len(fields) == 0 {
+		b.replyText(ctx, message, describeSettings(myConfig))
+		return
+	}
+
+	if !b.isGroupAdmin(message) {
+		b.replyText(ctx, message, localize(myConfig.Lang, "admin_only"))
+		return
+	}
+
+	myDb := b.app.Store
+	key := strings.ToLower(fields[0])
+	if key == "reset" {
+		if err := myDb.DeleteGroupSettings(ctx, message.Chat.ID); err != nil {
+			log.Printf("Error deleting group settings: %v", err)
+			return
+		}
+		b.replySettings(ctx, message)
+		return
+	}
+
+	if len(fields) != 2 {
+		b.replyText(ctx, message, localize(myConfig.Lang, "settings_usage", fmt.Errorf("expected a key and a value")))
+		return
+	}
+
+	settings, err := myDb.GetGroupSettings(ctx, message.Chat.ID)
+	if err != nil {
+		log.Printf("Error loading group settings: %v", err)
+		return
+	}
+	if err := applySetting(&settings, key, fields[1]); err != nil {
+		b.replyText(ctx, message, localize(myConfig.Lang, "settings_usage", err))
+		return
+	}
+	if err := myDb.SaveGroupSettings(ctx, settings); err != nil {
+		log.Printf("Error saving group settings: %v", err)
+		return
+	}
+	b.replySettings(ctx, message)
+}
+
+// applySetting validates value and stores it under key. "default" clears the override.
+func applySetting(settings *db.GroupSettings, key, value string) error {
+	// Timezones keep their case, e.g. "America/Sao_Paulo".
+	original := value
+	value = strings.ToLower(value)
+	if value == "default" {
+		value, original = "", ""
+	}
+
+	switch key {
+	case "lang":
+		if value != "" && !config.IsValidLanguage(value) {
+			return fmt.Errorf("invalid language: %q", value)
+		}
+		settings.Lang = value
+	case "provider":
+		if value != "" {
+			if _, err := llm.New(value); err != nil {
+				return err
+			}
+		}
+		settings.Provider = value
+	case "window":
+		settings.Window = 0
+		if value != "" {
+			d, err := parseWindow(value)
+			if err != nil || d < time.Minute {
+				return fmt.Errorf("invalid window: %q", value)
+			}
+			settings.Window = d
+		}
+	case "style":
+		if value != "" && !llm.IsValidStyle(value) {
+			return fmt.Errorf("invalid style %q (available: %s)", value, strings.Join(llm.Styles, ", "))
+		}
+		settings.Style = value
+	case "retention":
+		settings.RetentionDays = 0
+		if value == "forever" {
+			settings.RetentionDays = db.RetainForever
+		} else if value != "" {
+			days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+			if err != nil || days <= 0 {
+				return fmt.Errorf("invalid retention: %q", value)
+			}
+			settings.RetentionDays = days
+		}
+	case "redact":
+		if value != "" && !redact.IsValidMode(value) {
+			return fmt.Errorf("invalid redaction mode %q (available: %s)", value, strings.Join(redact.Modes, ", "))
+		}
+		settings.Redact = value
+	case "images":
+		switch value {
+		case "on", "off":
+			enabled := value == "on"
+			settings.DescribeImages = &enabled
+		case "":
+			settings.DescribeImages = nil
+		default:
+			return fmt.Errorf("invalid images value %q (expected on or off)", value)
+		}
+	case "timezone":
+		if original != "" && !config.IsValidTimezone(original) {
+			return fmt.Errorf("invalid timezone: %q", original)
+		}
+		settings.Timezone = original
+	default:
+		return fmt.Errorf("unknown setting: %q", key)
+	}
+	return nil
+}
+
+// replySettings answers with the settings now in effect.
+func (b *Bot) replySettings(ctx context.Context, message *tgbotapi.Message) {
+	myConfig, err := b.loadGroupConfig(ctx, message.Chat.ID)
+	if err != nil {
+		log.Printf("Error loading config: %v", err)
+		return
+	}
+	b.replyText(ctx, message, describeSettings(myConfig))
+}
+
+func describeSettings(myConfig *config.Config) string {
+	style := myConfig.Style
+	if style == "" {
+		style = "default"
+	}
+	images := "off"
+	if myConfig.DescribeImages {
+		images = fmt.Sprintf("on (max %d)", myConfig.MaxImages)
+	}
+	retention := "forever"
+	if myConfig.RetentionDays > 0 {
+		retention = fmt.Sprintf("%dd", myConfig.RetentionDays)
+	}
+	return localize(myConfig.Lang, "settings_status",
+		myConfig.Lang,
+		strings.Join(myConfig.LLMChain(), " → "),
+		myConfig.Window,
+		style,
+		retention,
+		myConfig.Redact,
+		images,
+		myConfig.Timezone,
+	)
+}
@@ -0,0 +1,71 @@
+package telegram
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"tldr-telegram-bot/internal/db"
+)
+
+func TestApplySetting(t *testing.T) {
+	on, off := true, false
+	// Every case starts from a group overriding every setting.
+	overridden := func() db.GroupSettings {
+		return db.GroupSettings{
+			GroupID: -100, Lang: "pt", Provider: "extractive", Window: time.Hour, Style: "brief",
+			RetentionDays: 30, Redact: "pii", DescribeImages: &on, Timezone: "Europe/Berlin",
+		}
+	}
+	with := func(change func(s *db.GroupSettings)) db.GroupSettings {
+		settings := overridden()
+		change(&settings)
+		return settings
+	}
+
+	for name, tc := range map[string]struct {
+		key, value string
+		want       db.GroupSettings
+		wantErr    bool
+	}{
+		"lang":                {key: "lang", value: "ES", want: with(func(s *db.GroupSettings) { s.Lang = "es" })},
+		"unknown lang":        {key: "lang", value: "xx", wantErr: true},
+		"provider":            {key: "provider", value: "extractive", want: overridden()},
+		"unknown provider":    {key: "provider", value: "nope", wantErr: true},
+		"window":              {key: "window", value: "90m", want: with(func(s *db.GroupSettings) { s.Window = 90 * time.Minute })},
+		"window in days":      {key: "window", value: "2d", want: with(func(s *db.GroupSettings) { s.Window = 48 * time.Hour })},
+		"window too short":    {key: "window", value: "30s", wantErr: true},
+		"style":               {key: "style", value: "bullets", want: with(func(s *db.GroupSettings) { s.Style = "bullets" })},
+		"unknown style":       {key: "style", value: "haiku", wantErr: true},
+		"retention":           {key: "retention", value: "7d", want: with(func(s *db.GroupSettings) { s.RetentionDays = 7 })},
+		"retention in days":   {key: "retention", value: "90", want: with(func(s *db.GroupSettings) { s.RetentionDays = 90 })},
+		"retention forever":   {key: "retention", value: "forever", want: with(func(s *db.GroupSettings) { s.RetentionDays = db.RetainForever })},
+		"retention of 0 days": {key: "retention", value: "0d", wantErr: true},
+		"redact":              {key: "redact", value: "all", want: with(func(s *db.GroupSettings) { s.Redact = "all" })},
+		"unknown redact mode": {key: "redact", value: "some", wantErr: true},
+		"images off":          {key: "images", value: "off", want: with(func(s *db.GroupSettings) { s.DescribeImages = &off })},
+		"images maybe":        {key: "images", value: "maybe", wantErr: true},
+		"timezone keeps case": {key: "timezone", value: "America/Sao_Paulo", want: with(func(s *db.GroupSettings) { s.Timezone = "America/Sao_Paulo" })},
+		"unknown timezone":    {key: "timezone", value: "Mars/Olympus", wantErr: true},
+		"unknown key":         {key: "color", value: "blue", wantErr: true},
+		"default lang":        {key: "lang", value: "default", want: with(func(s *db.GroupSettings) { s.Lang = "" })},
+		"default provider":    {key: "provider", value: "Default", want: with(func(s *db.GroupSettings) { s.Provider = "" })},
+		"default window":      {key: "window", value: "default", want: with(func(s *db.GroupSettings) { s.Window = 0 })},
+		"default style":       {key: "style", value: "default", want: with(func(s *db.GroupSettings) { s.Style = "" })},
+		"default retention":   {key: "retention", value: "default", want: with(func(s *db.GroupSettings) { s.RetentionDays = 0 })},
+		"default redact mode": {key: "redact", value: "default", want: with(func(s *db.GroupSettings) { s.Redact = "" })},
+		"default images":      {key: "images", value: "default", want: with(func(s *db.GroupSettings) { s.DescribeImages = nil })},
+		"default timezone":    {key: "timezone", value: "default", want: with(func(s *db.GroupSettings) { s.Timezone = "" })},
+	} {
+		t.Run(name, func(t *testing.T) {
+			settings := overridden()
+			err := applySetting(&settings, tc.key, tc.value)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && !reflect.DeepEqual(settings, tc.want) {
+				t.Errorf("settings = %+v, want %+v", settings, tc.want)
+			}
+		})
+	}
+}
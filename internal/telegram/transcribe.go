@@ -0,0 +1,99 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+
+	"tldr-telegram-bot/internal/db"
+	"tldr-telegram-bot/internal/transcribe"
+)
+
+// maxAudioSize is the largest file the Bot API lets bots download.
+const maxAudioSize = 20 << 20
+
+// isSpeech reports whether a message carries recorded speech worth transcribing.
+func isSpeech(msg db.Message) bool {
+	switch msg.Type {
+	case db.MessageVoice, db.MessageAudio, db.MessageVideoNote:
+		return msg.FileID != ""
+	}
+	return false
+}
+
+// transcribeMessage downloads the audio of a logged message, transcribes it and stores
+// the transcript, so it shows up in later summaries. It does nothing when no
+// transcription backend is configured or the group is not authorized.
+func (b *Bot) transcribeMessage(ctx context.Context, msg db.Message) {
+	myConfig, err := b.loadGroupConfig(ctx, msg.GroupID)
+	if err != nil {
+		log.Printf("Error loading config: %v", err)
+		return
+	}
+	if myConfig.Transcriber == "" || !b.isAuthorizedGroup(msg.GroupID) {
+		return
+	}
+
+	transcriber, err := transcribe.New(myConfig.Transcriber)
+	if err != nil {
+		log.Printf("Error creating transcriber: %v", err)
+		return
+	}
+
+	transcribeCtx, cancel := context.WithTimeout(ctx, myConfig.TranscribeTimeout)
+	defer cancel()
+
+	transcript, err := b.transcribeFile(transcribeCtx, transcriber, msg.FileID, myConfig.Lang)
+	if err != nil {
+		log.Printf("Error transcribing message %d of group %d: %v", msg.MessageID, msg.GroupID, err)
+		return
+	}
+	if transcript == "" {
+		return
+	}
+
+	if err := b.app.Store.SetTranscript(ctx, msg.GroupID, msg.MessageID, transcript); err != nil {
+		log.Printf("Error storing transcript: %v", err)
+	}
+}
+
+// transcribeFile streams a Telegram file to the transcriber.
+func (b *Bot) transcribeFile(ctx context.Context, transcriber transcribe.Transcriber, fileID, lang string) (string, error) {
+	file, name, err := b.downloadFile(ctx, fileID)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	return transcriber.Transcribe(ctx, io.LimitReader(file, maxAudioSize), name, lang)
+}
+
+// downloadFile opens a file sent to a chat, returning its body and base name.
+func (b *Bot) downloadFile(ctx context.Context, fileID string) (io.ReadCloser, string, error) {
+	fileURL, err := b.app.API.GetFileDirectURL(fileID)
+	if err != nil {
+		return nil, "", fmt.Errorf("getting file: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// The file URL embeds the bot token; keep it out of the logs.
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return nil, "", fmt.Errorf("downloading file: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, "", fmt.Errorf("downloading file: %s", resp.Status)
+	}
+	return resp.Body, path.Base(req.URL.Path), nil
+}
@@ -0,0 +1,110 @@
+package telegram
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"tldr-telegram-bot/internal/config"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// secretTokenHeader carries the secret registered with setWebhook on every update.
+const secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// webhookBuffer is how many received updates may wait for dispatch.
+const webhookBuffer = 100
+
+// webhookShutdownTimeout bounds how long requests being answered may delay a shutdown.
+const webhookShutdownTimeout = 5 * time.Second
+
+// listenForWebhook registers the webhook with Telegram, starts the HTTP server that
+// receives updates and returns them as a channel, like GetUpdatesChan does for polling.
+// When ctx is cancelled the server shuts down, then stopped receives nil; if the server
+// fails instead, for example because it cannot bind its address, stopped receives the error.
+func (b *Bot) listenForWebhook(ctx context.Context, myConfig *config.Config) (updates tgbotapi.UpdatesChannel, stopped <-chan error, err error) {
+	webhookURL, err := url.Parse(myConfig.WebhookURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid WEBHOOK_URL: %w", err)
+	}
+
+	// secret_token is not part of the library's WebhookConfig yet.
+	params := tgbotapi.Params{}
+	params.AddNonEmpty("url", myConfig.WebhookURL)
+	params.AddNonEmpty("secret_token", myConfig.WebhookSecret)
+	if _, err := b.app.API.MakeRequest("setWebhook", params); err != nil {
+		return nil, nil, fmt.Errorf("registering webhook: %w", err)
+	}
+
+	received := make(chan tgbotapi.Update, webhookBuffer)
+	path := webhookURL.Path
+	if path == "" {
+		path = "/"
+	}
+	mux := http.NewServeMux()
+	mux.Handle(path, webhookHandler(ctx, b.app.API, myConfig.WebhookSecret, received))
+
+	server := &http.Server{Addr: myConfig.WebhookListen, Handler: mux}
+	failed := make(chan error, 1)
+	go func() {
+		var err error
+		if myConfig.WebhookTLSCert != "" {
+			err = server.ListenAndServeTLS(myConfig.WebhookTLSCert, myConfig.WebhookTLSKey)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			failed <- fmt.Errorf("webhook server stopped: %w", err)
+		}
+	}()
+	done := make(chan error, 1)
+	go func() {
+		select {
+		case err := <-failed:
+			done <- err
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), webhookShutdownTimeout)
+			defer cancel()
+			if err := server.Shutdown(shutdownCtx); err != nil {
+				log.Printf("Error shutting down webhook server: %v", err)
+			}
+			done <- nil
+		}
+	}()
+
+	log.Printf("Listening for webhook updates on %s%s", myConfig.WebhookListen, path)
+	return received, done, nil
+}
+
+// webhookHandler accepts updates posted by Telegram and queues them on updates.
+// Requests without the secret token are rejected, so only Telegram can inject updates.
+// Once ctx is cancelled, updates are refused so that Telegram delivers them again later.
+func webhookHandler(ctx context.Context, api *tgbotapi.BotAPI, secret string, updates chan<- tgbotapi.Update) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(secretTokenHeader)), []byte(secret)) != 1 {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		update, err := api.HandleUpdate(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Blocking here applies backpressure: Telegram retries updates that time out.
+		select {
+		case updates <- *update:
+			w.WriteHeader(http.StatusOK)
+		case <-ctx.Done():
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		case <-r.Context().Done():
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package telegram
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	testSecret = "s3cret"
+	testUpdate = `{"update_id":7,"message":{"message_id":1,"date":0,"chat":{"id":-100,"type":"supergroup"},"text":"hi"}}`
+)
+
+// postUpdate posts body to handler with the given secret token header, if any.
+func postUpdate(handler http.Handler, secret, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	if secret != "" {
+		req.Header.Set(secretTokenHeader, secret)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestWebhookHandlerChecksSecret(t *testing.T) {
+	for name, secret := range map[string]string{
+		"missing secret": "",
+		"wrong secret":   "guess",
+		"secret prefix":  "s3cre",
+	} {
+		t.Run(name, func(t *testing.T) {
+			updates := make(chan tgbotapi.Update, 1)
+			handler := webhookHandler(context.Background(), &tgbotapi.BotAPI{}, testSecret, updates)
+
+			if rec := postUpdate(handler, secret, testUpdate); rec.Code != http.StatusForbidden {
+				t.Errorf("status = %d, want 403", rec.Code)
+			}
+			if len(updates) != 0 {
+				t.Error("update queued without the right secret")
+			}
+		})
+	}
+}
+
+func TestWebhookHandlerQueuesUpdates(t *testing.T) {
+	updates := make(chan tgbotapi.Update, 1)
+	handler := webhookHandler(context.Background(), &tgbotapi.BotAPI{}, testSecret, updates)
+
+	if rec := postUpdate(handler, testSecret, testUpdate); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	select {
+	case update := <-updates:
+		if update.UpdateID != 7 || update.Message == nil || update.Message.Text != "hi" {
+			t.Errorf("queued update = %+v, want update 7", update)
+		}
+	default:
+		t.Fatal("update not queued")
+	}
+
+	if rec := postUpdate(handler, testSecret, "{"); rec.Code != http.StatusBadRequest {
+		t.Errorf("status for an invalid body = %d, want 400", rec.Code)
+	}
+}
+
+func TestWebhookHandlerRefusesDuringShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// Unbuffered and never read, so only the cancelled ctx can answer.
+	updates := make(chan tgbotapi.Update)
+	handler := webhookHandler(ctx, &tgbotapi.BotAPI{}, testSecret, updates)
+
+	if rec := postUpdate(handler, testSecret, testUpdate); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", rec.Code)
+	}
+}
@@ -0,0 +1,65 @@
+// Package transcribe turns voice messages into text through pluggable speech-to-text backends.
+package transcribe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Transcriber converts recorded speech to text.
+type Transcriber interface {
+	// Transcribe reads the audio file named filename from audio. lang is a hint
+	// ("pt", "en", ...) that backends may ignore.
+	Transcribe(ctx context.Context, audio io.Reader, filename, lang string) (string, error)
+}
+
+// Factory builds a Transcriber from the environment.
+type Factory func() (Transcriber, error)
+
+var (
+	backendsMu sync.RWMutex
+	backends   = map[string]Factory{}
+)
+
+// Register makes a backend available under the given name.
+// It panics if the name is registered twice, like database/sql drivers do.
+func Register(name string, factory Factory) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	if factory == nil {
+		panic("transcribe: Register factory is nil")
+	}
+	if _, dup := backends[name]; dup {
+		panic("transcribe: Register called twice for backend " + name)
+	}
+	backends[name] = factory
+}
+
+// New returns the Transcriber registered under name.
+func New(name string) (Transcriber, error) {
+	backendsMu.RLock()
+	factory, ok := backends[name]
+	backendsMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown transcription backend %q (available: %v)", name, Backends())
+	}
+	return factory()
+}
+
+// Backends returns the sorted names of the registered backends.
+func Backends() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
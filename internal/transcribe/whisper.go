@@ -0,0 +1,89 @@
+package transcribe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+)
+
+func init() {
+	Register("whisper", func() (Transcriber, error) { return NewWhisperClient() })
+}
+
+// WhisperClient talks to the HTTP server shipped with whisper.cpp. The server must run
+// with --convert, since Telegram voice notes are Ogg/Opus rather than WAV.
+type WhisperClient struct {
+	// BaseURL is the server root, e.g. "http://localhost:8080".
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+func NewWhisperClient() (*WhisperClient, error) {
+	baseURL := os.Getenv("WHISPER_URL")
+	if baseURL == "" {
+		return nil, fmt.Errorf("WHISPER_URL environment variable is not set")
+	}
+
+	return &WhisperClient{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: &http.Client{},
+	}, nil
+}
+
+type whisperResponse struct {
+	Text  string `json:"text"`
+	Error string `json:"error"`
+}
+
+// Transcribe posts the audio to the /inference endpoint.
+func (c *WhisperClient) Transcribe(ctx context.Context, audio io.Reader, filename, lang string) (string, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(part, audio); err != nil {
+		return "", fmt.Errorf("reading audio: %w", err)
+	}
+	form.WriteField("response_format", "json")
+	form.WriteField("temperature", "0.0")
+	if lang != "" {
+		form.WriteField("language", lang)
+	}
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/inference", &body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("whisper returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	var result whisperResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	if result.Error != "" {
+		return "", fmt.Errorf("whisper: %s", result.Error)
+	}
+	return strings.TrimSpace(result.Text), nil
+}
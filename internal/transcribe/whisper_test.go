@@ -0,0 +1,93 @@
+package transcribe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeWhisper serves /inference with handle, after checking the form the client sent.
+func fakeWhisper(t *testing.T, wantLang string, handle func(w http.ResponseWriter)) *WhisperClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/inference" {
+			t.Errorf("request = %s %s, want POST /inference", r.Method, r.URL.Path)
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("parsing form: %v", err)
+		} else {
+			file, header, err := r.FormFile("file")
+			if err != nil {
+				t.Errorf("file field: %v", err)
+			} else {
+				audio, _ := io.ReadAll(file)
+				if header.Filename != "voice.ogg" || string(audio) != "OggS audio" {
+					t.Errorf("file = %q with %q, want voice.ogg with the audio", header.Filename, audio)
+				}
+			}
+			if got := r.FormValue("response_format"); got != "json" {
+				t.Errorf("response_format = %q, want json", got)
+			}
+			if got := r.FormValue("language"); got != wantLang {
+				t.Errorf("language = %q, want %q", got, wantLang)
+			}
+		}
+		handle(w)
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv("WHISPER_URL", server.URL+"/")
+	client, err := NewWhisperClient()
+	if err != nil {
+		t.Fatalf("NewWhisperClient: %v", err)
+	}
+	return client
+}
+
+func TestWhisperTranscribe(t *testing.T) {
+	for _, lang := range []string{"de", ""} {
+		client := fakeWhisper(t, lang, func(w http.ResponseWriter) {
+			fmt.Fprint(w, `{"text":" Hallo zusammen.\n"}`)
+		})
+
+		transcript, err := client.Transcribe(context.Background(), strings.NewReader("OggS audio"), "voice.ogg", lang)
+		if err != nil {
+			t.Fatalf("Transcribe(%q): %v", lang, err)
+		}
+		if transcript != "Hallo zusammen." {
+			t.Errorf("Transcribe(%q) = %q, want the trimmed text", lang, transcript)
+		}
+	}
+}
+
+func TestWhisperErrors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		handle  func(w http.ResponseWriter)
+		wantErr string
+	}{
+		"status": {
+			handle:  func(w http.ResponseWriter) { http.Error(w, "model not loaded", http.StatusInternalServerError) },
+			wantErr: "model not loaded",
+		},
+		"error field": {
+			handle:  func(w http.ResponseWriter) { fmt.Fprint(w, `{"error":"failed to read audio"}`) },
+			wantErr: "failed to read audio",
+		},
+		"malformed response": {
+			handle:  func(w http.ResponseWriter) { fmt.Fprint(w, `not json`) },
+			wantErr: "failed to decode response",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client := fakeWhisper(t, "en", tc.handle)
+			transcript, err := client.Transcribe(context.Background(), strings.NewReader("OggS audio"), "voice.ogg", "en")
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("Transcribe = %q, %v, want an error mentioning %q", transcript, err, tc.wantErr)
+			}
+		})
+	}
+}